@@ -6,7 +6,8 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"strconv"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
 )
 
 type WebData struct {
@@ -14,6 +15,7 @@ type WebData struct {
 	Text    string
 	Matches []string
 	NFAJson template.JS
+	Error   string
 }
 
 type NFAData struct {
@@ -25,6 +27,7 @@ type StateData struct {
 	Name        string           `json:"name"`
 	Transitions []TransitionData `json:"transitions"`
 	IsFinal     bool             `json:"isFinal"`
+	Groups      string           `json:"groups"`
 }
 
 type TransitionData struct {
@@ -37,22 +40,28 @@ func webServer() {
 	fmt.Println("webserver??")
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
-			regex := r.FormValue("regex")
+			pattern := r.FormValue("regex")
 			text := r.FormValue("text")
-			regexEngine, err := NewRegexEngine(regex)
-			matches := regexEngine.matchLine([]byte(text))
+			regexEngine, err := regex.Compile(pattern)
 			if err != nil {
-				log.Fatal(err)
+				tmpl := template.Must(template.ParseFiles("index.html"))
+				w.WriteHeader(http.StatusBadRequest)
+				err = tmpl.Execute(w, WebData{Regex: pattern, Text: text, Error: describeRegexError(err)})
+				if err != nil {
+					log.Fatal(err)
+				}
+				return
 			}
+			matches := regexEngine.FindAll([]byte(text), -1)
 
-			nfaData := convertNFAToData(regexEngine.nfa)
+			nfaData := convertNFAToData(regexEngine.Graph())
 
 			// Debug: Print NFA structure to terminal
-			fmt.Printf("=== NFA DEBUG (regex: %s) ===\n", regex)
+			fmt.Printf("=== NFA DEBUG (regex: %s) ===\n", pattern)
 			fmt.Printf("Init State: %s\n", nfaData.InitState)
 			fmt.Println("ALL STATES:")
 			for stateName, state := range nfaData.States {
-				fmt.Printf("State %s: isFinal=%t, transitions=%d\n", stateName, state.IsFinal, len(state.Transitions))
+				fmt.Printf("State %s: isFinal=%t, groups=%q, transitions=%d\n", stateName, state.IsFinal, state.Groups, len(state.Transitions))
 				for _, t := range state.Transitions {
 					fmt.Printf("  -> %s (label: \"%s\", epsilon: %t)\n", t.To, t.Label, t.IsEpsilon)
 				}
@@ -61,7 +70,7 @@ func webServer() {
 
 			nfaJson, _ := json.Marshal(nfaData)
 			data := WebData{
-				Regex:   regex,
+				Regex:   pattern,
 				Text:    text,
 				Matches: bytesToStrings(matches),
 				NFAJson: template.JS(nfaJson),
@@ -82,49 +91,29 @@ func webServer() {
 	}
 }
 
-func convertNFAToData(nfa NFA) NFAData {
+func convertNFAToData(graph regex.Graph) NFAData {
 	statesData := make(map[string]StateData)
 
-	for name, state := range nfa.States {
-		transitions := make([]TransitionData, len(state.transitions))
-		for i, trans := range state.transitions {
-			label := getMatcherLabel(trans.matcher)
+	for _, state := range graph.States {
+		transitions := make([]TransitionData, len(state.Transitions))
+		for i, trans := range state.Transitions {
 			transitions[i] = TransitionData{
-				To:        trans.to,
-				Label:     label,
-				IsEpsilon: trans.matcher.isEpsilon(),
+				To:        trans.To,
+				Label:     trans.Label,
+				IsEpsilon: trans.IsEpsilon,
 			}
 		}
 
-		statesData[strconv.Itoa(name)] = StateData{
-			Name:        state.name,
+		statesData[state.Name] = StateData{
+			Name:        state.Name,
 			Transitions: transitions,
-			IsFinal:     state.isFinal,
+			IsFinal:     state.IsFinal,
+			Groups:      state.Groups,
 		}
 	}
 
 	return NFAData{
-		InitState: nfa.getInitialState().name,
+		InitState: graph.InitState,
 		States:    statesData,
 	}
 }
-
-func getMatcherLabel(matcher Matcher) string {
-	if matcher.isEpsilon() {
-		return "ε"
-	}
-	switch m := matcher.(type) {
-	case LiteralMatcher:
-		return string(m.char)
-	case DigitMatcher:
-		return "\\d"
-	case WordMatcher:
-		return "\\w"
-	case CharacterGroupMatcher:
-		return m.label
-	case AnyCharMatcher:
-		return "."
-	default:
-		return "?"
-	}
-}
@@ -0,0 +1,287 @@
+package regex
+
+import "unicode/utf8"
+
+// ------------------ Aho-Corasick ------------------
+// Patterns built around an alternation of literals (denylists, keyword
+// lists) are searched with an Aho-Corasick automaton: a trie of all the
+// literals plus failure links, so the input is read once no matter how many
+// alternatives there are.
+//
+// The automaton reports every literal ending at a position, the one we
+// return is picked the same way the NFA would: the leftmost start wins and
+// on a tie the alternative written first in the pattern.
+//
+// The alternation is found in the parsed tree, so it may be written in a
+// group, e.g. (foo|bar), and surrounded by assertions, e.g. \b(?:foo|bar)\b
+// or what -w adds. The assertions don't move where a match starts, so the
+// automaton finds the places a match can start and the program checks the
+// pattern there. Case insensitive literals are folded into the automaton:
+// every character is read as the smallest one of its fold orbit.
+// ---------------------------------------------
+
+type acNode struct {
+	children map[rune]int
+	fail     int
+	// alternative ending exactly at this node, -1 if none
+	out int
+	// closest node on the failure chain with an out, -1 if none
+	outLink int
+	// number of characters from the root
+	depth int
+}
+
+// literalAlternation is a pattern built around an alternation of literals.
+type literalAlternation struct {
+	// the characters of every alternative, in byte mode the bytes
+	literals [][]rune
+	// the literals match case insensitively
+	fold bool
+	// the capturing groups around the alternation, they capture what it
+	// matched
+	groups []int
+	// the alternation comes with assertions which the program checks
+	assertions bool
+}
+
+type ahoCorasick struct {
+	literalAlternation
+	decoder decoder
+	nodes   []acNode
+	// the longest literal, in characters
+	maxLen int
+}
+
+// literalAlternatives finds the alternation of literals the tree n is built
+// around: made of Literals or Texts, maybe in capturing groups, maybe
+// between assertions. All the literals fold or none do.
+func literalAlternatives(n Node, d decoder) (literalAlternation, bool) {
+	la := literalAlternation{}
+	if concat, ok := n.(Concat); ok {
+		core := []Node{}
+		for _, sub := range concat.Subs {
+			switch sub.(type) {
+			case Anchor, Lookaround:
+				la.assertions = true
+			default:
+				core = append(core, sub)
+			}
+		}
+		if len(core) != 1 {
+			return la, false
+		}
+		n = core[0]
+	}
+	for {
+		group, ok := n.(Group)
+		if !ok || group.Atomic {
+			break
+		}
+		la.groups = append(la.groups, group.Index)
+		n = group.Sub
+	}
+
+	alternate, ok := n.(Alternate)
+	if !ok {
+		return la, false
+	}
+	for i, sub := range alternate.Subs {
+		chars, fold, ok := literalChars(sub, d)
+		if !ok || len(chars) == 0 || (i > 0 && fold != la.fold) {
+			return la, false
+		}
+		la.literals = append(la.literals, chars)
+		la.fold = fold
+	}
+
+	return la, true
+}
+
+// literalChars returns the characters n matches one after the other and
+// whether they fold, false if it isn't a run of literals all folded or not.
+func literalChars(n Node, d decoder) ([]rune, bool, bool) {
+	literals := []Literal{}
+	switch n := n.(type) {
+	case Literal:
+		literals = append(literals, n)
+	case Text:
+		for _, c := range n.Chars {
+			literals = append(literals, Literal{Char: c, Fold: n.Fold})
+		}
+	case Concat:
+		for _, sub := range n.Subs {
+			literal, ok := sub.(Literal)
+			if !ok {
+				return nil, false, false
+			}
+			literals = append(literals, literal)
+		}
+	default:
+		return nil, false, false
+	}
+
+	chars := []rune{}
+	for _, literal := range literals {
+		if literal.Fold != literals[0].Fold {
+			return nil, false, false
+		}
+		// in byte mode it matches the bytes of its UTF-8 encoding, like
+		// compiler.charMatchers
+		if !d.byteMode || literal.Char <= 0xFF {
+			chars = append(chars, literal.Char)
+			continue
+		}
+		for _, b := range utf8.AppendRune(nil, literal.Char) {
+			chars = append(chars, rune(b))
+		}
+	}
+
+	return chars, literals[0].Fold, true
+}
+
+func newAhoCorasick(la literalAlternation, d decoder) *ahoCorasick {
+	ac := &ahoCorasick{
+		literalAlternation: la,
+		decoder:            d,
+		nodes:              []acNode{{children: map[rune]int{}, out: -1, outLink: -1}},
+	}
+
+	for i, literal := range la.literals {
+		node := 0
+		for j, c := range literal {
+			c = ac.key(c)
+			next, ok := ac.nodes[node].children[c]
+			if !ok {
+				next = len(ac.nodes)
+				ac.nodes = append(ac.nodes, acNode{children: map[rune]int{}, out: -1, outLink: -1, depth: j + 1})
+				ac.nodes[node].children[c] = next
+			}
+			node = next
+		}
+		// keep the first of duplicated alternatives
+		if ac.nodes[node].out == -1 {
+			ac.nodes[node].out = i
+		}
+		ac.maxLen = max(ac.maxLen, len(literal))
+	}
+
+	// failure links breadth first, a node's fail is shallower than the node
+	queue := []int{}
+	for _, child := range ac.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for c, child := range ac.nodes[node].children {
+			fail := ac.nodes[node].fail
+			for fail != 0 && !ac.hasChild(fail, c) {
+				fail = ac.nodes[fail].fail
+			}
+			if next, ok := ac.nodes[fail].children[c]; ok && next != child {
+				fail = next
+			}
+			ac.nodes[child].fail = fail
+			if ac.nodes[fail].out != -1 {
+				ac.nodes[child].outLink = fail
+			} else {
+				ac.nodes[child].outLink = ac.nodes[fail].outLink
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	return ac
+}
+
+func (ac *ahoCorasick) hasChild(node int, c rune) bool {
+	_, ok := ac.nodes[node].children[c]
+	return ok
+}
+
+// key is how the automaton reads the character c.
+func (ac *ahoCorasick) key(c rune) rune {
+	if ac.fold {
+		return ac.decoder.foldKey(c)
+	}
+	return c
+}
+
+// find returns the bounds of the leftmost-first alternative found at or after
+// index, laid out like capture slots, nil if there is none.
+func (ac *ahoCorasick) find(input []byte, index int) []int {
+	bestStart, bestEnd, best := -1, -1, -1
+	// where the last maxLen characters read start, to know where a literal
+	// ending at the current one starts
+	starts := make([]int, ac.maxLen)
+	bestChar := 0
+	node := 0
+	for i, k := index, 0; i < len(input); k++ {
+		// nothing starting after bestStart can beat it
+		if best != -1 && k >= bestChar+ac.maxLen {
+			break
+		}
+		c, width := ac.decoder.decode(input, i)
+		c = ac.key(c)
+		starts[k%ac.maxLen] = i
+		i += width
+
+		for node != 0 && !ac.hasChild(node, c) {
+			node = ac.nodes[node].fail
+		}
+		if next, ok := ac.nodes[node].children[c]; ok {
+			node = next
+		}
+
+		out := node
+		if ac.nodes[out].out == -1 {
+			out = ac.nodes[out].outLink
+		}
+		for out != -1 {
+			alternative := ac.nodes[out].out
+			first := k + 1 - ac.nodes[out].depth
+			start := starts[first%ac.maxLen]
+			if best == -1 || start < bestStart || (start == bestStart && alternative < best) {
+				bestStart, bestEnd, best, bestChar = start, i, alternative, first
+			}
+			out = ac.nodes[out].outLink
+		}
+	}
+
+	if best == -1 {
+		return nil
+	}
+
+	return []int{bestStart, bestEnd}
+}
+
+// search returns the capture slots of the leftmost-first match of the
+// pattern, p being its program, that starts at or after index. nil if there
+// is none.
+func (ac *ahoCorasick) search(p *prog, input []byte, index int, anchored bool) []int {
+	if anchored {
+		return p.matchAt(input, index)
+	}
+	for index <= len(input) {
+		found := ac.find(input, index)
+		if found == nil {
+			return nil
+		}
+		if ac.assertions {
+			// a match can only start where a literal does
+			if caps := p.matchAt(input, found[0]); caps != nil {
+				return caps
+			}
+			index = found[0] + p.charWidth(input, found[0])
+			continue
+		}
+
+		caps := newCaps(p.numSlots)
+		for _, slot := range append([]int{0}, ac.groups...) {
+			caps[2*slot], caps[2*slot+1] = found[0], found[1]
+		}
+		return caps
+	}
+
+	return nil
+}
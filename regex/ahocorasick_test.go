@@ -0,0 +1,185 @@
+package regex
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLiteralAlternatives(t *testing.T) {
+	data := []struct {
+		pattern    string
+		opts       Options
+		literals   []string
+		fold       bool
+		groups     []int
+		assertions bool
+	}{
+		{pattern: "foo|bar|bazqux", literals: []string{"foo", "bar", "bazqux"}},
+		{pattern: "a\\.b|c\\|d", literals: []string{"a.b", "c|d"}},
+		{pattern: "foo", literals: nil},
+		{pattern: "foo|", literals: nil},
+		{pattern: "(foo|bar)", literals: []string{"foo", "bar"}, groups: []int{1}},
+		{pattern: "((?:foo|bar))", literals: []string{"foo", "bar"}, groups: []int{1}},
+		{pattern: "\\b(?:foo|bar)\\b", literals: []string{"foo", "bar"}, assertions: true},
+		{pattern: "(?<!\\w)(?:foo|bar)(?!\\w)", literals: []string{"foo", "bar"}, assertions: true},
+		{pattern: "(?i)foo|bar", literals: []string{"foo", "bar"}, fold: true},
+		{pattern: "foo|bar", opts: Options{CaseInsensitive: true}, literals: []string{"foo", "bar"}, fold: true},
+		{pattern: "(?i:foo)|bar", literals: nil},
+		{pattern: "(?>foo|bar)", literals: nil},
+		{pattern: "x(?:foo|bar)", literals: nil},
+		{pattern: "fo+|bar", literals: nil},
+		{pattern: "\\d|bar", literals: nil},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			node, err := ParseWithOptions(item.pattern, item.opts)
+			if err != nil {
+				t.Fatalf("Parsing %v failed: %v", item.pattern, err)
+			}
+			la, ok := literalAlternatives(node, decoder{})
+			literals := []string{}
+			for _, literal := range la.literals {
+				literals = append(literals, string(literal))
+			}
+
+			if ok != (item.literals != nil) || (ok && !stringSliceEqual(literals, item.literals)) {
+				t.Fatalf("Expected literals %v, got: %v (ok: %v)", item.literals, literals, ok)
+			}
+			if ok && (la.fold != item.fold || !slices.Equal(la.groups, item.groups) || la.assertions != item.assertions) {
+				t.Errorf("Expected fold %v, groups %v, assertions %v, got: %v, %v, %v", item.fold, item.groups, item.assertions, la.fold, la.groups, la.assertions)
+			}
+		})
+	}
+}
+
+func TestAhoCorasickMatching(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "foo|bar|bazqux",
+			input:   "xxbarfoo bazqu bazqux",
+			matches: []string{"bar", "foo", "bazqux"},
+		},
+		{
+			// leftmost-first: the alternative written first wins
+			pattern: "foo|foobar",
+			input:   "foobar",
+			matches: []string{"foo"},
+		},
+		{
+			pattern: "foobar|foo",
+			input:   "foobar foo",
+			matches: []string{"foobar", "foo"},
+		},
+		{
+			// the leftmost start wins even when it ends later
+			pattern: "bc|abcd",
+			input:   "abcd",
+			matches: []string{"abcd"},
+		},
+		{
+			pattern: "he|she|his|hers",
+			input:   "ushers",
+			matches: []string{"she"},
+		},
+		{
+			pattern: "cat|dog",
+			input:   "cow",
+			matches: []string{},
+		},
+		{
+			pattern: "(cat|dog)",
+			input:   "hotdog catalog",
+			matches: []string{"dog", "cat"},
+		},
+		{
+			pattern: "(?i)cat|dog",
+			input:   "CAT Dog cOw",
+			matches: []string{"CAT", "Dog"},
+		},
+		{
+			// K (U+212A, the Kelvin sign) folds to k
+			pattern: "(?i)kelvin|ohm",
+			input:   "\u212Aelvin OHM",
+			matches: []string{"\u212Aelvin", "OHM"},
+		},
+		{
+			pattern: "\\b(?:foo|foobar)\\b",
+			input:   "foobar foo foox",
+			matches: []string{"foobar", "foo"},
+		},
+		{
+			pattern: "(?<!\\w)(?:he|she)(?!\\w)",
+			input:   "ushers she he",
+			matches: []string{"she", "he"},
+		},
+		{
+			pattern: "^(?:ab|b)",
+			input:   "ab b",
+			matches: []string{"ab"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			if re.ac == nil {
+				t.Fatalf("Expected %v to be matched by Aho-Corasick", item.pattern)
+			}
+			matchesString := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestAhoCorasickMatchesNFA(t *testing.T) {
+	terms := []string{}
+	for i := 0; i < 300; i++ {
+		terms = append(terms, fmt.Sprintf("term%d", i*7))
+	}
+	pattern := strings.Join(terms, "|")
+	re := MustCompile(pattern)
+	input := []byte("a term7 b term700 term1400 term21x term2093")
+
+	for i := 0; i < len(input); i++ {
+		found := re.ac.find(input, i)
+		caps := re.prog.search(input, i, false)
+
+		if !slices.Equal(found, caps) {
+			t.Errorf("At %v expected NFA result %v, got: %v", i, caps, found)
+		}
+	}
+}
+
+func TestAhoCorasickSubmatchesMatchStdlib(t *testing.T) {
+	patterns := []string{
+		"(foo|bar)",
+		"((foo|foobar))",
+		"\\b(foo|bar)\\b",
+		"(?i)(foo|bar)",
+		"^(?:foo|bar)",
+		"(?:foo|bar)$",
+	}
+	input := "foobar FOO bar foo barfoo xfoo bar"
+
+	for _, pattern := range patterns {
+		t.Run(fmt.Sprintf("Checking pattern %v", pattern), func(t *testing.T) {
+			re := MustCompile(pattern)
+			if re.ac == nil {
+				t.Fatalf("Expected %v to be matched by Aho-Corasick", pattern)
+			}
+			expected := regexp.MustCompile(pattern).FindAllSubmatchIndex([]byte(input), -1)
+			got := re.FindAllSubmatchIndex([]byte(input), -1)
+
+			if !slices.EqualFunc(got, expected, slices.Equal) {
+				t.Errorf("Expected submatches %v, got: %v", expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package regex
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ------------------ AST ------------------
+// The parser turns a pattern into a tree of nodes, compile.go turns the tree
+// into an NFA. In between the tree can be inspected, rewritten or printed
+// without matching anything.
+//
+// Only what changes the meaning of the pattern is kept: flags are applied to
+// the nodes they cover, non-capturing groups disappear into their parent,
+// \Q...\E becomes literals and classes become sorted character ranges. So
+// many patterns share a tree, String prints the canonical one, e.g. a{0,}
+// and (?:a)* both print a*.
+//
+// The printed pattern parses back to the same tree as long as Simplify
+// didn't rewrite it (in byte mode the characters from 0x80 to 0xFF print as
+// the runes of the same value, so only outside of it). A simplified tree
+// prints as a pattern matching the same strings.
+// ---------------------------------------------
+
+// Node is a node of the tree, one of the types below.
+type Node interface {
+	// String returns the canonical pattern for the node.
+	String() string
+	write(sb *strings.Builder)
+}
+
+// Empty matches the empty string, e.g. the right side of "a|".
+type Empty struct{}
+
+// Literal matches the character Char, or when Fold is set any character
+// equal to it once case folded.
+type Literal struct {
+	Char rune
+	Fold bool
+}
+
+// Text matches the characters Chars one after the other, each one case
+// folded when Fold is set. The parser only builds Literals, Simplify merges
+// runs of them into a Text.
+type Text struct {
+	Chars []rune
+	Fold  bool
+}
+
+// CharClass matches a character in Ranges, or when Negated one outside them.
+// Ranges are sorted and don't touch each other.
+type CharClass struct {
+	Ranges  []CharRange
+	Negated bool
+}
+
+// AnyChar is '.', which only matches '\n' when MatchNewline is set.
+type AnyChar struct {
+	MatchNewline bool
+}
+
+type AnchorKind int
+
+const (
+	// ^ and $
+	AnchorStart AnchorKind = iota
+	AnchorEnd
+	// ^ and $ in multi-line mode, also after / before a '\n'
+	AnchorLineStart
+	AnchorLineEnd
+	// \A, \z and \Z
+	AnchorTextStart
+	AnchorTextEnd
+	AnchorTextEndNewline
+	// \b and \B
+	AnchorWordBoundary
+	AnchorNotWordBoundary
+)
+
+// Anchor matches a position rather than a character.
+type Anchor struct {
+	Kind AnchorKind
+}
+
+// Group is a capturing group, Index being its number and Name its name if
+// it has one, or an atomic group which doesn't capture.
+type Group struct {
+	Sub    Node
+	Index  int
+	Name   string
+	Atomic bool
+}
+
+// Lookaround is (?=...), (?!...), (?<=...) or (?<!...).
+type Lookaround struct {
+	Sub      Node
+	Behind   bool
+	Negative bool
+}
+
+// Repeat matches Sub from Min to Max times, Max is -1 when there's no upper
+// bound. A lazy repeat prefers fewer repetitions, a possessive one never
+// gives any back.
+type Repeat struct {
+	Sub        Node
+	Min        int
+	Max        int
+	Lazy       bool
+	Possessive bool
+}
+
+// Concat matches its Subs one after the other.
+type Concat struct {
+	Subs []Node
+}
+
+// Alternate matches one of its Subs, the first one that leads to a match.
+type Alternate struct {
+	Subs []Node
+}
+
+// Backref matches what group Index captured, case folded when Fold is set.
+// Name is the group's name when the pattern referred to it by name.
+type Backref struct {
+	Index int
+	Name  string
+	Fold  bool
+}
+
+func (n Empty) String() string      { return nodeString(n) }
+func (n Literal) String() string    { return nodeString(n) }
+func (n Text) String() string       { return nodeString(n) }
+func (n CharClass) String() string  { return nodeString(n) }
+func (n AnyChar) String() string    { return nodeString(n) }
+func (n Anchor) String() string     { return nodeString(n) }
+func (n Group) String() string      { return nodeString(n) }
+func (n Lookaround) String() string { return nodeString(n) }
+func (n Repeat) String() string     { return nodeString(n) }
+func (n Concat) String() string     { return nodeString(n) }
+func (n Alternate) String() string  { return nodeString(n) }
+func (n Backref) String() string    { return nodeString(n) }
+
+func nodeString(n Node) string {
+	var sb strings.Builder
+	n.write(&sb)
+
+	return sb.String()
+}
+
+func (n Empty) write(sb *strings.Builder) {}
+
+func (n Literal) write(sb *strings.Builder) {
+	if n.Fold {
+		sb.WriteString("(?i:")
+		writeChar(sb, n.Char, `\.+*?()|[]{}^$`)
+		sb.WriteByte(')')
+		return
+	}
+	writeChar(sb, n.Char, `\.+*?()|[]{}^$`)
+}
+
+func (n Text) write(sb *strings.Builder) {
+	if n.Fold {
+		sb.WriteString("(?i:")
+	}
+	for _, c := range n.Chars {
+		writeChar(sb, c, `\.+*?()|[]{}^$`)
+	}
+	if n.Fold {
+		sb.WriteByte(')')
+	}
+}
+
+func (n CharClass) write(sb *strings.Builder) {
+	if name, ok := perlClass(n); ok {
+		sb.WriteString(name)
+		return
+	}
+	sb.WriteByte('[')
+	if n.Negated {
+		sb.WriteByte('^')
+	}
+	for _, r := range n.Ranges {
+		writeChar(sb, r.from, `\[]-^`)
+		if r.to == r.from {
+			continue
+		}
+		if r.to > r.from+1 {
+			sb.WriteByte('-')
+		}
+		writeChar(sb, r.to, `\[]-^`)
+	}
+	sb.WriteByte(']')
+}
+
+func (n AnyChar) write(sb *strings.Builder) {
+	if n.MatchNewline {
+		sb.WriteString("(?s:.)")
+		return
+	}
+	sb.WriteByte('.')
+}
+
+var anchorSyntax = map[AnchorKind]string{
+	AnchorStart:           "^",
+	AnchorEnd:             "$",
+	AnchorLineStart:       "(?m:^)",
+	AnchorLineEnd:         "(?m:$)",
+	AnchorTextStart:       `\A`,
+	AnchorTextEnd:         `\z`,
+	AnchorTextEndNewline:  `\Z`,
+	AnchorWordBoundary:    `\b`,
+	AnchorNotWordBoundary: `\B`,
+}
+
+func (n Anchor) write(sb *strings.Builder) {
+	sb.WriteString(anchorSyntax[n.Kind])
+}
+
+func (n Group) write(sb *strings.Builder) {
+	switch {
+	case n.Atomic:
+		sb.WriteString("(?>")
+	case n.Name != "":
+		sb.WriteString("(?P<" + n.Name + ">")
+	default:
+		sb.WriteByte('(')
+	}
+	n.Sub.write(sb)
+	sb.WriteByte(')')
+}
+
+func (n Lookaround) write(sb *strings.Builder) {
+	sb.WriteString("(?")
+	if n.Behind {
+		sb.WriteByte('<')
+	}
+	if n.Negative {
+		sb.WriteByte('!')
+	} else {
+		sb.WriteByte('=')
+	}
+	n.Sub.write(sb)
+	sb.WriteByte(')')
+}
+
+func (n Repeat) write(sb *strings.Builder) {
+	switch n.Sub.(type) {
+	case Empty, Text, Concat, Alternate, Repeat:
+		writeGrouped(sb, n.Sub)
+	default:
+		n.Sub.write(sb)
+	}
+
+	switch {
+	case n.Min == 0 && n.Max == -1:
+		sb.WriteByte('*')
+	case n.Min == 1 && n.Max == -1:
+		sb.WriteByte('+')
+	case n.Min == 0 && n.Max == 1:
+		sb.WriteByte('?')
+	case n.Min == n.Max:
+		fmt.Fprintf(sb, "{%v}", n.Min)
+	case n.Max == -1:
+		fmt.Fprintf(sb, "{%v,}", n.Min)
+	default:
+		fmt.Fprintf(sb, "{%v,%v}", n.Min, n.Max)
+	}
+
+	if n.Lazy {
+		sb.WriteByte('?')
+	} else if n.Possessive {
+		sb.WriteByte('+')
+	}
+}
+
+func (n Concat) write(sb *strings.Builder) {
+	for i := 0; i < len(n.Subs); i++ {
+		switch sub := n.Subs[i].(type) {
+		case Empty, Concat, Alternate:
+			writeGrouped(sb, sub)
+		case Literal:
+			if !sub.Fold {
+				sub.write(sb)
+				continue
+			}
+			// a run of folded literals shares one flag group
+			sb.WriteString("(?i:")
+			for ; i < len(n.Subs); i++ {
+				next, ok := n.Subs[i].(Literal)
+				if !ok || !next.Fold {
+					break
+				}
+				next.Fold = false
+				next.write(sb)
+			}
+			i--
+			sb.WriteByte(')')
+		case Backref:
+			// \1 followed by 0 would read as \10
+			if i+1 < len(n.Subs) && startsWithDigit(n.Subs[i+1]) {
+				writeGrouped(sb, sub)
+				continue
+			}
+			sub.write(sb)
+		default:
+			sub.write(sb)
+		}
+	}
+}
+
+func startsWithDigit(n Node) bool {
+	printed := n.String()
+	return printed != "" && printed[0] >= '0' && printed[0] <= '9'
+}
+
+func (n Alternate) write(sb *strings.Builder) {
+	for i, sub := range n.Subs {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		if _, ok := sub.(Alternate); ok {
+			writeGrouped(sb, sub)
+			continue
+		}
+		sub.write(sb)
+	}
+}
+
+func (n Backref) write(sb *strings.Builder) {
+	if n.Fold {
+		sb.WriteString("(?i:")
+	}
+	if n.Name != "" {
+		sb.WriteString(`\k<` + n.Name + ">")
+	} else {
+		fmt.Fprintf(sb, `\%v`, n.Index)
+	}
+	if n.Fold {
+		sb.WriteByte(')')
+	}
+}
+
+// writeGrouped writes n in a non-capturing group.
+func writeGrouped(sb *strings.Builder, n Node) {
+	sb.WriteString("(?:")
+	n.write(sb)
+	sb.WriteByte(')')
+}
+
+// writeChar writes c, escaped if it's one of special or not printable.
+func writeChar(sb *strings.Builder, c rune, special string) {
+	switch {
+	case c < 0x80 && strings.ContainsRune(special, c):
+		sb.WriteByte('\\')
+		sb.WriteRune(c)
+	case c == '\t':
+		sb.WriteString(`\t`)
+	case c == '\n':
+		sb.WriteString(`\n`)
+	case c == '\r':
+		sb.WriteString(`\r`)
+	case c == '\f':
+		sb.WriteString(`\f`)
+	case c == '\v':
+		sb.WriteString(`\v`)
+	case unicode.IsPrint(c):
+		sb.WriteRune(c)
+	default:
+		fmt.Fprintf(sb, `\x{%X}`, c)
+	}
+}
+
+// perlClass returns the escape, e.g. \d, standing for exactly the characters
+// of n, false if there's none.
+func perlClass(n CharClass) (string, bool) {
+	if n.Negated {
+		return "", false
+	}
+	for i, ranges := range perlClassRanges {
+		if slices.Equal(n.Ranges, ranges) {
+			return `\` + perlClassEscapes[i:i+1], true
+		}
+	}
+
+	return "", false
+}
+
+// perlClassRanges[i] are the merged ranges of the escape perlClassEscapes[i],
+// worked out once since every class of a pattern is compared to them.
+const perlClassEscapes = "dwsDWS"
+
+var perlClassRanges = func() [][]CharRange {
+	all := [][]CharRange{}
+	for _, esc := range []byte(perlClassEscapes) {
+		ranges, _ := classEscape(esc)
+		all = append(all, mergeRanges(ranges))
+	}
+	return all
+}()
@@ -0,0 +1,108 @@
+package regex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestASTCanonicalString(t *testing.T) {
+	data := []struct {
+		pattern   string
+		canonical string
+	}{
+		{"abc", "abc"},
+		{"a{0,}", "a*"},
+		{"a{1,}", "a+"},
+		{"a{0,1}", "a?"},
+		{"a{2,2}", "a{2}"},
+		{"a{2,}?", "a{2,}?"},
+		{"a{2,5}+", "a{2,5}+"},
+		{"(?:a)*", "a*"},
+		{"(?:ab)*", "(?:ab)*"},
+		{"(?:a|b)c", "(?:a|b)c"},
+		{"(?:a|b)|c", "a|b|c"},
+		{"x(?:ab)y", "xaby"},
+		{"(?:a*)*", "(?:a*)*"},
+		{"(a)(?P<n>b)(?<m>c)", "(a)(?P<n>b)(?P<m>c)"},
+		{"(?>a|ab)c", "(?>a|ab)c"},
+		{"(?<=a)b(?!c)", "(?<=a)b(?!c)"},
+		{"(?i)ab1", "(?i:ab)1"},
+		{"(?i:a)b", "(?i:a)b"},
+		{"(?s).", "(?s:.)"},
+		{"(?m)^a$", "(?m:^)a(?m:$)"},
+		{"(?U)a*b*?", "a*?b*"},
+		{"\\Qa.b\\E", "a\\.b"},
+		{"a\\Q\\E", "a(?:)"},
+		{"a|", "a|"},
+		{"[a-cb]", "[a-c]"},
+		{"[0-9]", "\\d"},
+		{"[^a]", "[^a]"},
+		{"[ab]", "[ab]"},
+		{"[\\]\\-^]", "[\\-\\]\\^]"},
+		{"\\x41\\t", "A\\t"},
+		{"\\x{1}", "\\x{1}"},
+		{"\\bA\\B\\z\\Z", "\\bA\\B\\z\\Z"},
+		{"(a)\\1", "(a)\\1"},
+		{"(?i)(a)\\1", "((?i:a))(?i:\\1)"},
+		{"(?<n>a)\\k<n>", "(?P<n>a)\\k<n>"},
+		{"(a)\\10", "(a)(?:\\1)0"},
+	}
+
+	for _, d := range data {
+		node, err := Parse(d.pattern)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", d.pattern, err)
+		}
+		if got := node.String(); got != d.canonical {
+			t.Errorf("Expected %q to print as %q, got %q", d.pattern, d.canonical, got)
+		}
+	}
+}
+
+func TestASTRoundTrip(t *testing.T) {
+	patterns := []string{
+		"", "a|", "(|a)b", "()", "a(?:)*", "(?:a|b)*c", "x(a|b|)+?y", "a{3,5}b{2}c{4,}",
+		"(a*)*", "(?:a+)?", "[[:alpha:]\\p{Greek}]+", "\\pL\\PN", "[^\\d\\s]", "\\W\\S\\D",
+		"(?i)straße|ǅ", "(?i)[a-z]", "(?i)1(a)\\1", "(?is)a.(?-i:b)(?m)^$",
+		"(?<y>\\d{4})-(?P<m>\\d\\d)\\k<y>", "(?>a+)b++c?+", "(?<!a)(?<=b)(?=c)(?!d)",
+		"\\Qa*\\E+", "\\x{10FFFF}\\n\\r\\f\\v", "ą\\x{7F}[\\x{0}-\\x{1F}]",
+		"(a)(b)(c)(d)(e)(f)(g)(h)(i)(j)\\10\\1 0", "\\A\\bx\\B\\z\\Z",
+	}
+
+	for _, pattern := range patterns {
+		node, err := Parse(pattern)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", pattern, err)
+		}
+		printed := node.String()
+		reparsed, err := Parse(printed)
+		if err != nil {
+			t.Fatalf("Error parsing %q, printed from %q: %v", printed, pattern, err)
+		}
+		if !reflect.DeepEqual(node, reparsed) {
+			t.Errorf("Expected %q printed as %q to parse to the same tree, got %#v and %#v", pattern, printed, node, reparsed)
+		}
+		if again := reparsed.String(); again != printed {
+			t.Errorf("Expected %q to print as %q again, got %q", pattern, printed, again)
+		}
+	}
+}
+
+func TestASTCompileMatchesPattern(t *testing.T) {
+	patterns := []string{
+		"(a|ab)(c|bcd)(d*)", "(?i)x[a-c]+", "a{2,3}?b", "(?:foo|bar)+", "\\d+\\.\\d*", "(\\w+) \\1",
+	}
+	input := "abcd xABc aaab foobarfoo 3.14 1. hello hello"
+
+	for _, pattern := range patterns {
+		node, err := Parse(pattern)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", pattern, err)
+		}
+		want := MustCompile(pattern).FindAllSubmatchIndex([]byte(input), -1)
+		got := MustCompile(node.String()).FindAllSubmatchIndex([]byte(input), -1)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %q printed as %q to match at %v, got %v", pattern, node.String(), want, got)
+		}
+	}
+}
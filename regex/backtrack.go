@@ -0,0 +1,228 @@
+package regex
+
+import "slices"
+
+// ------------------ Backtracking ------------------
+// Depth first search over the program, used for patterns the Pike VM can't
+// run (backreferences, atomic groups). Transitions are pushed in reverse so
+// transitions[0] is explored first, the first final state popped is the
+// leftmost-first match.
+//
+// An atomic group remembers how high the stack was when the path entered
+// it. Leaving the group cuts the stack back to that height, which throws
+// away every alternative left inside the group.
+//
+// A path coming back to an instruction without having consumed anything is
+// dropped, it would only repeat itself.
+//
+// Whether a path from an instruction at some position leads to a match
+// doesn't depend on how the path got there, unless a backreference comes
+// later. So those (instruction, position) pairs are only explored once: the
+// first time either matched, and the search is over, or didn't. Like Go's
+// backtracker this keeps the search O(len(insts) * len(input)), also across
+// the start positions of an unanchored search.
+//
+// Inside an atomic group a path failing after it left the group also threw
+// away the group's other alternatives, skipping it wouldn't. So the pairs
+// there are only remembered for the entry into the group they were explored
+// in, where those alternatives are gone anyway.
+// ---------------------------------------------
+
+type StackData struct {
+	pc   int
+	i    int
+	caps []int
+	// where the groups the path is currently inside started, a group's
+	// capture only changes once the group is closed
+	open []int
+	// the atomic groups the path is inside, innermost last
+	atomic []atomicEntry
+	// the instructions the path went through since it last consumed input,
+	// going back to one of them would loop forever, e.g. in (a*)*+
+	seen []int
+}
+
+// atomicEntry is a path entering an atomic group: the stack height at that
+// point and a number telling the entry from the others.
+type atomicEntry struct {
+	height int
+	id     int
+}
+
+type MemoryGroup struct {
+	start int
+	end   int
+}
+
+// Memory is what the current path has captured so far, matchers that look
+// back at earlier groups (backreferences) read it.
+type Memory struct {
+	caps []int
+}
+
+// group returns the capture stored at slot, false if the group didn't match
+// (yet).
+func (m Memory) group(slot int) (MemoryGroup, bool) {
+	if slot < 0 || slot+1 >= len(m.caps) || m.caps[slot] < 0 || m.caps[slot+1] < 0 {
+		return MemoryGroup{}, false
+	}
+
+	return MemoryGroup{start: m.caps[slot], end: m.caps[slot+1]}, true
+}
+
+type Stack struct {
+	data []StackData
+}
+
+// visited is the set of (instruction, position) pairs explored already, one
+// row of bits per position from base on. The pairs inside an atomic group
+// are kept apart per entry into the group.
+type visited struct {
+	bits    []uint64
+	base    int
+	width   int
+	atomic  map[[3]int]bool
+	entries int
+}
+
+func (p *prog) newVisited(base int) *visited {
+	return &visited{base: base, width: len(p.insts), atomic: map[[3]int]bool{}}
+}
+
+// insert adds the pair, false if it was there already.
+func (v *visited) insert(pc int, pos int, atomic []atomicEntry) bool {
+	if len(atomic) > 0 {
+		key := [3]int{pc, pos, atomic[len(atomic)-1].id}
+		if v.atomic[key] {
+			return false
+		}
+		v.atomic[key] = true
+		return true
+	}
+	bit := (pos-v.base)*v.width + pc
+	for bit/64 >= len(v.bits) {
+		v.bits = append(v.bits, 0)
+	}
+	if v.bits[bit/64]&(1<<(bit%64)) != 0 {
+		return false
+	}
+	v.bits[bit/64] |= 1 << (bit % 64)
+
+	return true
+}
+
+// run returns the capture slots of the leftmost-first match starting exactly
+// at index, nil if there is none.
+func (p *prog) run(line []byte, index int) []int {
+	return p.backtrack(line, index, newCaps(p.numSlots), p.newVisited(index), -1)
+}
+
+// runWithCaps is run starting from groups already captured, e.g. by the
+// pattern around a lookaround, for a match ending exactly at end unless it's
+// -1.
+func (p *prog) runWithCaps(line []byte, index int, caps []int, end int) []int {
+	return p.backtrack(line, index, caps, p.newVisited(index), end)
+}
+
+// backtrack is runWithCaps skipping the pairs in visited, which failed to
+// match already, e.g. from an earlier start position. It adds the ones it
+// explores.
+func (p *prog) backtrack(line []byte, index int, caps []int, visited *visited, end int) []int {
+	stack := Stack{}
+	caps = slices.Clone(caps)
+	caps[0] = index
+	stack.push(StackData{pc: p.start, i: index, caps: caps, open: make([]int, p.numSlots/2)})
+	for stack.length() > 0 {
+		item := stack.pop()
+		if !p.readsCaps[item.pc] && !visited.insert(item.pc, item.i, item.atomic) {
+			continue
+		}
+		inst := p.insts[item.pc]
+		p.compueGroup(inst, &item)
+		if inst.isFinal {
+			if end >= 0 && item.i != end {
+				continue
+			}
+			item.caps[1] = item.i
+			return item.caps
+		}
+		memory := Memory{caps: item.caps}
+
+		for i := len(inst.transitions) - 1; i >= 0; i-- {
+			transition := inst.transitions[i]
+
+			if item.i >= len(line) && !transition.matcher.isEpsilon() {
+				// nothing left to read, only a backreference to a group
+				// which captured the empty string can still match
+				if _, ok := transition.matcher.(BackreferenceMatcher); !ok {
+					continue
+				}
+			}
+			match := transition.matcher.match(line, item.i, memory)
+
+			if match.match {
+				newIndex := item.i
+				if !transition.matcher.isEpsilon() {
+					newIndex += match.consume
+				}
+				next := StackData{pc: transition.to, i: newIndex, caps: item.caps, open: item.open, atomic: item.atomic}
+				if match.caps != nil {
+					next.caps = match.caps
+				}
+				if newIndex == item.i {
+					if transition.to == item.pc || slices.Contains(item.seen, transition.to) {
+						continue
+					}
+					next.seen = append(slices.Clip(item.seen), item.pc)
+				}
+				switch transition.matcher.(type) {
+				case AtomicStartMatcher:
+					visited.entries++
+					next.atomic = append(slices.Clip(item.atomic), atomicEntry{height: stack.length(), id: visited.entries})
+				case AtomicEndMatcher:
+					height := item.atomic[len(item.atomic)-1].height
+					stack.data = stack.data[:height]
+					next.atomic = item.atomic[:len(item.atomic)-1]
+				}
+				stack.push(next)
+			}
+		}
+	}
+	return nil
+}
+
+// compueGroup records the groups opened or closed by inst. Paths share their
+// slots until one of them changes, so they're copied first.
+func (p *prog) compueGroup(inst instruction, item *StackData) {
+	if len(inst.startSlots) == 0 && len(inst.endSlots) == 0 {
+		return
+	}
+	item.caps = slices.Clone(item.caps)
+	item.open = slices.Clone(item.open)
+
+	for _, slot := range inst.startSlots {
+		item.open[slot/2] = item.i
+	}
+
+	for _, slot := range inst.endSlots {
+		item.caps[slot-1] = item.open[slot/2]
+		item.caps[slot] = item.i
+	}
+}
+
+// ------------------ Stack ------------------
+
+func (s *Stack) push(item StackData) {
+	s.data = append(s.data, item)
+}
+
+func (s *Stack) pop() StackData {
+	lastItem := s.data[len(s.data)-1]
+	s.data = s.data[:len(s.data)-1]
+
+	return lastItem
+}
+
+func (s Stack) length() int {
+	return len(s.data)
+}
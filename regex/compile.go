@@ -0,0 +1,571 @@
+package regex
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ------------------ Compiler ------------------
+// Turns the tree built by the parser (see ast.go) into an NFA, one fragment
+// per node glued together with epsilon transitions (Thompson's
+// construction). Every fragment has one initial and one final state.
+//
+// All the fragments are built in the same NFA and only refer to its states
+// by position, gluing two of them is adding a transition. So the NFA is
+// built in time linear in its size, even for patterns with thousands of
+// alternatives.
+//
+// The NFA is then flattened into a program by compileProg.
+// ---------------------------------------------
+
+// fragment is the part of the NFA matching one node, from the state initial
+// to the state final. final has no transitions until the fragment is glued to
+// what comes after it.
+type fragment struct {
+	initial int
+	final   int
+}
+
+type compiler struct {
+	nfa NFA
+	// reads the input the way the pattern was read, given to the matchers
+	decoder decoder
+	// number of capturing groups of the whole pattern, for the programs of
+	// lookarounds
+	numGroups int
+}
+
+// compileNode builds the NFA of the tree n, numGroups being the number of
+// capturing groups of the pattern.
+func compileNode(n Node, numGroups int, d decoder) (NFA, error) {
+	c := compiler{decoder: d, numGroups: numGroups}
+	f, err := c.compile(n)
+	if err != nil {
+		return NFA{}, err
+	}
+	c.nfa.initial, c.nfa.final = f.initial, f.final
+
+	return c.nfa, nil
+}
+
+// oneStep returns the fragment going from its initial to its final state
+// through matcher.
+func (c *compiler) oneStep(matcher Matcher) (fragment, error) {
+	q1 := c.nfa.newState()
+	q2 := c.nfa.newState()
+	c.nfa.addTransition(q1, q2, matcher)
+
+	return fragment{initial: q1, final: q2}, nil
+}
+
+func (c *compiler) compile(n Node) (fragment, error) {
+	switch n := n.(type) {
+	case Empty:
+		return c.oneStep(EpsilonMatcher{})
+	case Literal:
+		return c.literal(n)
+	case Text:
+		return c.text(n)
+	case CharClass:
+		return c.oneStep(c.classMatcher(n))
+	case AnyChar:
+		return c.oneStep(AnyCharMatcher{decoder: c.decoder, matchNewline: n.MatchNewline})
+	case Anchor:
+		return c.oneStep(anchorMatcher(n.Kind))
+	case Backref:
+		return c.oneStep(BackreferenceMatcher{decoder: c.decoder, groupId: strconv.Itoa(n.Index), fold: n.Fold})
+	case Group:
+		return c.group(n)
+	case Lookaround:
+		return c.lookaround(n)
+	case Repeat:
+		return c.repeat(n)
+	case Concat:
+		return c.concat(n)
+	case Alternate:
+		return c.alternate(n)
+	}
+
+	return fragment{}, fmt.Errorf("unknown node %T", n)
+}
+
+// literal matches the character, or any character it folds to when case
+// insensitive.
+func (c *compiler) literal(n Literal) (fragment, error) {
+	return c.chain(c.charMatchers(nil, n.Char, n.Fold))
+}
+
+// text matches the characters one after the other with a single chain of
+// states, rather than a fragment per character glued to the next.
+func (c *compiler) text(n Text) (fragment, error) {
+	matchers := []Matcher{}
+	for _, char := range n.Chars {
+		matchers = c.charMatchers(matchers, char, n.Fold)
+	}
+
+	return c.chain(matchers)
+}
+
+// charMatchers appends the matchers of the character to matchers. In byte
+// mode characters above 0xFF don't fit in a byte, they match their UTF-8
+// encoding byte by byte.
+func (c *compiler) charMatchers(matchers []Matcher, char rune, fold bool) []Matcher {
+	if fold {
+		if orbit := c.decoder.foldOrbit(char); len(orbit) > 1 {
+			return append(matchers, NewCharacterGroupMatcher(c.decoder, nil, orbit, false, "(?i)"+string(char)))
+		}
+	}
+	if !c.decoder.byteMode || char <= 0xFF {
+		return append(matchers, LiteralMatcher{decoder: c.decoder, char: char})
+	}
+	for _, b := range utf8.AppendRune(nil, char) {
+		matchers = append(matchers, LiteralMatcher{decoder: c.decoder, char: rune(b)})
+	}
+
+	return matchers
+}
+
+// chain returns the fragment going from its initial to its final state
+// through one transition per matcher.
+//
+//	-->(q0) -m1-> (q1) -m2-> ... -mk-> (qk)
+func (c *compiler) chain(matchers []Matcher) (fragment, error) {
+	q0 := c.nfa.newState()
+	last := q0
+	for _, matcher := range matchers {
+		next := c.nfa.newState()
+		c.nfa.addTransition(last, next, matcher)
+		last = next
+	}
+
+	return fragment{initial: q0, final: last}, nil
+}
+
+func (c *compiler) classMatcher(n CharClass) Matcher {
+	name, _ := perlClass(n)
+	switch name {
+	case `\d`:
+		return NewDigitMatcher(c.decoder)
+	case `\w`:
+		return NewWordMatcher(c.decoder)
+	}
+
+	return NewCharacterGroupMatcher(c.decoder, n.Ranges, nil, n.Negated, n.String())
+}
+
+func anchorMatcher(kind AnchorKind) Matcher {
+	switch kind {
+	case AnchorStart, AnchorLineStart:
+		return StartOfStringMatcher{multiLine: kind == AnchorLineStart}
+	case AnchorEnd, AnchorLineEnd:
+		return EndOfStringMatcher{multiLine: kind == AnchorLineEnd}
+	case AnchorTextStart:
+		return StartOfTextMatcher{}
+	case AnchorTextEnd, AnchorTextEndNewline:
+		return EndOfTextMatcher{beforeFinalNewline: kind == AnchorTextEndNewline}
+	}
+
+	return WordBoundaryMatcher{negated: kind == AnchorNotWordBoundary}
+}
+
+func (c *compiler) group(n Group) (fragment, error) {
+
+	//   -->(q1) ------->   ( N(s) )    ------->   (q2)
+	// 1. Add new start state (q1)
+	// 2. Add end state (q2)
+	// 3. Add eppsilon transition from q1 to init state of N(s)
+	// 4. Add epsilon transition from ending states of N(s) to q2
+
+	// an atomic group gets no id and its states no group markers
+	sub, err := c.compile(n.Sub)
+	if err != nil {
+		return fragment{}, err
+	}
+
+	start := c.nfa.newState()
+	end := c.nfa.newState()
+	if !n.Atomic {
+		c.nfa.States[start].startGroup = []string{strconv.Itoa(n.Index)}
+		c.nfa.States[end].endGroup = []string{strconv.Itoa(n.Index)}
+	}
+
+	c.nfa.addTransition(start, sub.initial, EpsilonMatcher{})
+	c.nfa.addTransition(sub.final, end, EpsilonMatcher{})
+	f := fragment{initial: start, final: end}
+	if n.Atomic {
+		f = c.atomic(f)
+	}
+
+	return f, nil
+}
+
+// lookaround compiles the sub-pattern to its own program, the lookaround is
+// a single transition running it. See lookaround.go.
+func (c *compiler) lookaround(n Lookaround) (fragment, error) {
+	sub, err := compileNode(n.Sub, c.numGroups, c.decoder)
+	if err != nil {
+		return fragment{}, err
+	}
+
+	matcher := LookaroundMatcher{
+		negative: n.Negative,
+		behind:   n.Behind,
+		slots:    groupSlots(n.Sub),
+		label:    n.String(),
+	}
+	matcher.prog = compileProg(&sub, c.numGroups, c.decoder)
+	matcher.maxLen = matcher.prog.maxLength()
+
+	return c.oneStep(matcher)
+}
+
+// repeat builds the loop of a quantifier. A lazy loop prefers to exit, a
+// possessive one is wrapped into an atomic group.
+func (c *compiler) repeat(n Repeat) (fragment, error) {
+	if n.Min == 0 && n.Max == -1 && canBeEmpty(n.Sub) {
+		// an iteration of x* which matched nothing comes back to q1, already
+		// entered at that position, and is dropped with the exit it would
+		// have led to. Like Go, x* is compiled as (?:x+)? instead, the exit
+		// then comes after the iteration.
+		plus := Repeat{Sub: n.Sub, Min: 1, Max: -1, Lazy: n.Lazy}
+		return c.repeat(Repeat{Sub: plus, Min: 0, Max: 1, Lazy: n.Lazy, Possessive: n.Possessive})
+	}
+	lazy := n.Lazy
+
+	var leftAtom fragment
+	switch {
+	case n.Min == 1 && n.Max == -1:
+		/*
+					  ┌────────ε────────┐
+					  ▼          	    │
+			(q1) -ε> (q2) -condition-> (q3) -ε> ((q4))
+
+			1. Create start state q1
+			2. Create end state q4
+			3. Add epsilon transition from q1 to q2
+			4. Add epsilon transition from q3 to q2 (loop repetition), by default it's greedy so prioritize it rather than exit the loop
+			5. Add epsilon transition from q3 to q4
+
+		*/
+		body, err := c.compile(n.Sub)
+		if err != nil {
+			return fragment{}, err
+		}
+
+		q1 := c.nfa.newState()
+		q4 := c.nfa.newState()
+
+		c.nfa.addTransition(q1, body.initial, EpsilonMatcher{})
+		// Greedy matcher, the loop is added first (the exit for a lazy one)
+		c.nfa.addRepeatChoice(body.final, body.initial, q4, lazy)
+
+		leftAtom = fragment{initial: q1, final: q4}
+	case n.Min == 0 && n.Max == 1:
+		/*
+
+			(q1) -ε> (q2) -condition-> (q3) -ε> ((q4))
+			  │                                    ▲
+			  └────────────────ε───────────────────┘
+
+			1. Create start state q1
+			2. Create end state q4
+			3. Add epsilon transition from q1 to q2
+			4. Add epsilon transition from q1 to q4 (exit), by default it's greedy so prioritize entering condition
+			5. Add epsilon transition from q3 to q4
+
+		*/
+		body, err := c.compile(n.Sub)
+		if err != nil {
+			return fragment{}, err
+		}
+
+		q1 := c.nfa.newState()
+		q4 := c.nfa.newState()
+
+		// Greedy matcher, entering is added first (the exit for a lazy one)
+		c.nfa.addRepeatChoice(q1, body.initial, q4, lazy)
+		c.nfa.addTransition(body.final, q4, EpsilonMatcher{})
+
+		leftAtom = fragment{initial: q1, final: q4}
+	case n.Min == 0 && n.Max == -1:
+		/*
+			   ┌───────────────ε──────────────┐
+			   ▼                              │
+			(q1) -ε> (q2) -condition-> (q3) ─┘
+			   │
+			   └───ε───> ((q4))
+
+			1. Create the loop state q1, which is also the start state
+			2. Create end state q4
+			3. Add epislon transition from q1 to q4
+			4. Add epsilon transition from q1 to q2, by default it's greedy so prioritize it rather than exit the loop
+			5. Add epsilon transition from q3 back to q1 (loop repetition), every
+			   iteration goes through the same choice so one coming back to q1
+			   without having consumed anything is dropped.
+		*/
+		body, err := c.compile(n.Sub)
+		if err != nil {
+			return fragment{}, err
+		}
+
+		q1 := c.nfa.newState()
+		q4 := c.nfa.newState()
+
+		c.nfa.addRepeatChoice(q1, body.initial, q4, lazy)
+		c.nfa.addTransition(body.final, q1, EpsilonMatcher{})
+
+		leftAtom = fragment{initial: q1, final: q4}
+	default:
+		lowewrBound, upperBound := n.Min, n.Max
+		isUpperBoundInfinity := upperBound < 0
+		// exact quantifiers
+		/*
+			(q1) -condition X-> (q2) ──────ε─────▶ (q1->q3) -condition X-> (q2->q4)
+			│                      │ 				│                   		   │
+			└──────────────────────┘				└──────────────────────────────┘
+					 NFA 								NFA (copy) repeat X times
+		*/
+		// --- loop x times ----
+		// 1. Compile the sub-pattern again, a copy of its NFA
+		// 2. Add epsilon transition from q2 to q3 state
+		// 3. q4 is the new end of the chain
+
+		// at least n times, with no upper limit
+		/*
+
+																										┌────────ε────────────┐
+																										▼          	    	  │
+
+			(q1) -condition X-> (q2) ──────ε─────▶ (q1->q3) -condition X-> (q2->q4) ──────ε─────▶ (q1->q5) -condition X-> (q2->q6) ───────ε─────▶ q7 (end state)
+			│                      │ 				│                   		   │				│                   		   │
+			└──────────────────────┘				└──────────────────────────────┘				└──────────────────────────────┘
+					 NFA 							  NFA (copy) repeat n-1 times						NFA (copy) repeat n times
+		*/
+
+		// at least n times with no upper
+		// -- loop x times
+		// 1. Compile the sub-pattern again
+		// 2. Add epsilon transition from q2 to q3 state
+		// 3. q4 is the new end of the chain
+		// 4. add epsilion transition q6 to q5 (last copy of nfa)
+
+		// between n and m times
+		/*
+
+
+			(q1) -condition X-> (q2) ──────ε─────▶ (q1->q3) -condition X-> (q2->q4) ──────ε─────▶ (q1->q5) -condition X-> (q2->q6) ───────ε─────▶ q7 (end state)
+			│                      │ 				│                   		   │				│                   		   │
+			└──────────────────────┘				└──────────────────────────────┘				└──────────────────────────────┘
+					 NFA 							  NFA (copy) repeat n times						NFA (copy) repeat m-n times
+					 			  │
+			  											         			  																		     ▲
+								 												 												└──────────────ε─────────┘
+																																						 ▲
+								 												 └───────────────────────────────ε───────────────────────────────────────┘
+		*/
+		// at least n times with no upper
+		// first iteration
+		// 	1. Compile the sub-pattern again, every copy has its own group
+		// 	   markers
+		// 	2. Add epsilon transition from q2 to new q3 state, once there are
+		// 	   n copies also the exit to q7 ordered by addRepeatChoice
+		// 	3. q4 is the new end of the chain
+		// 	4. add epsilion transition from n copy (end stsate) to n copy init state
+		// If repeats more than m-n times
+		// 	1. add epslion from end state q4 to q7
+
+		q1 := c.nfa.newState()
+		endState := c.nfa.newState()
+
+		// with no upper bound the last copy loops, so there has to be one
+		copies := upperBound
+		if isUpperBoundInfinity {
+			copies = max(lowewrBound, 1)
+		}
+
+		last, loopStart := q1, q1
+		for i := 0; i < copies; i++ {
+			body, err := c.compile(n.Sub)
+			if err != nil {
+				return fragment{}, err
+			}
+			loopStart = body.initial
+
+			if i >= lowewrBound {
+				// enough repetitions already, it's possible to leave
+				c.nfa.addRepeatChoice(last, loopStart, endState, lazy)
+			} else {
+				c.nfa.addTransition(last, body.initial, EpsilonMatcher{})
+			}
+			last = body.final
+		}
+
+		if isUpperBoundInfinity {
+			c.nfa.addRepeatChoice(last, loopStart, endState, lazy)
+		} else {
+			c.nfa.addTransition(last, endState, EpsilonMatcher{})
+		}
+
+		leftAtom = fragment{initial: q1, final: endState}
+	}
+	if n.Possessive {
+		leftAtom = c.atomic(leftAtom)
+	}
+
+	return leftAtom, nil
+}
+
+func (c *compiler) concat(n Concat) (fragment, error) {
+	if len(n.Subs) == 0 {
+		return c.oneStep(EpsilonMatcher{})
+	}
+	left, err := c.compile(n.Subs[0])
+	if err != nil {
+		return fragment{}, err
+	}
+	for _, sub := range n.Subs[1:] {
+		right, err := c.compile(sub)
+		if err != nil {
+			return fragment{}, err
+		}
+		c.nfa.addTransition(left.final, right.initial, EpsilonMatcher{})
+		left.final = right.final
+	}
+
+	return left, nil
+}
+
+func (c *compiler) alternate(n Alternate) (fragment, error) {
+	// 	          ε                ε
+	//       +-------> ( N(s) ) ------->+
+	//       |          	            |
+	//   -->(q1)                       (q2)<--
+	//       |         	 	            |
+	//       +-------> ( N(t) ) ------->+
+	//           ε                ε
+
+	// 1. Create a new start state q1
+	// 2. Add epsilon transition to starting point of every alternative, in
+	//    order so the first one is preferred
+	// 3. Create end state q2
+	// 4. Add epsilon transition from ending state of every alternative to
+	//    end state
+
+	start := c.nfa.newState()
+	end := c.nfa.newState()
+	for _, sub := range n.Subs {
+		alternative, err := c.compile(sub)
+		if err != nil {
+			return fragment{}, err
+		}
+		c.nfa.addTransition(start, alternative.initial, EpsilonMatcher{})
+		c.nfa.addTransition(alternative.final, end, EpsilonMatcher{})
+	}
+
+	return fragment{initial: start, final: end}, nil
+}
+
+// atomic wraps f into an atomic group: once a path leaves it, the other ways
+// f could have matched are never tried.
+//
+//	-->(q1) -atomic start-> ( N(s) ) -ε-> (q2) -atomic end-> (q3)
+func (c *compiler) atomic(f fragment) fragment {
+	q1 := c.nfa.newState()
+	q2 := c.nfa.newState()
+	q3 := c.nfa.newState()
+
+	c.nfa.addTransition(q1, f.initial, AtomicStartMatcher{})
+	c.nfa.addTransition(f.final, q2, EpsilonMatcher{})
+	c.nfa.addTransition(q2, q3, AtomicEndMatcher{})
+
+	return fragment{initial: q1, final: q3}
+}
+
+// canBeEmpty reports whether n may match the empty string.
+func canBeEmpty(n Node) bool {
+	switch n := n.(type) {
+	case Literal, Text, CharClass, AnyChar:
+		return false
+	case Group:
+		return canBeEmpty(n.Sub)
+	case Repeat:
+		return n.Min == 0 || canBeEmpty(n.Sub)
+	case Concat:
+		for _, sub := range n.Subs {
+			if !canBeEmpty(sub) {
+				return false
+			}
+		}
+		return true
+	case Alternate:
+		for _, sub := range n.Subs {
+			if canBeEmpty(sub) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Empty, anchors, lookarounds and backreferences to an empty group
+	return true
+}
+
+// groupSlots returns the capture slots of the capturing groups in n.
+func groupSlots(n Node) []int {
+	switch n := n.(type) {
+	case Group:
+		slots := groupSlots(n.Sub)
+		if !n.Atomic {
+			slots = append(slots, 2*n.Index, 2*n.Index+1)
+		}
+		return slots
+	case Lookaround:
+		return groupSlots(n.Sub)
+	case Repeat:
+		return groupSlots(n.Sub)
+	case Concat:
+		return subsGroupSlots(n.Subs)
+	case Alternate:
+		return subsGroupSlots(n.Subs)
+	}
+
+	return nil
+}
+
+func subsGroupSlots(subs []Node) []int {
+	slots := []int{}
+	for _, sub := range subs {
+		slots = append(slots, groupSlots(sub)...)
+	}
+
+	return slots
+}
+
+// nodeStates estimates the number of states the NFA of n has, so that a
+// quantifier copying a large fragment can be rejected before it's built.
+func nodeStates(n Node) int {
+	switch n := n.(type) {
+	case Text:
+		return len(n.Chars) + 1
+	case Group:
+		return nodeStates(n.Sub) + 5
+	case Repeat:
+		copies := max(n.Max, n.Min, 1)
+		return copies*nodeStates(n.Sub) + 5
+	case Concat:
+		total := 0
+		for _, sub := range n.Subs {
+			total += nodeStates(sub)
+		}
+		return total
+	case Alternate:
+		total := 2
+		for _, sub := range n.Subs {
+			total += nodeStates(sub)
+		}
+		return total
+	}
+
+	return 2
+}
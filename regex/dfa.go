@@ -0,0 +1,271 @@
+package regex
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ------------------ Lazy DFA ------------------
+// Subset construction done on the fly: a DFA state is the set of
+// instructions the program can be in after an epsilon closure, and the
+// transition for a byte is only computed the first time that byte is seen
+// in that state. Afterwards stepping is a single table lookup per byte,
+// which is a lot cheaper than pushing threads around in the Pike VM.
+//
+// The search is unanchored, the start instruction is added again at every
+// position, and it only answers whether there is a match at all.
+//
+// Cached states are bounded by a memory budget. When the budget is exceeded
+// the cache is flushed, and if that keeps happening during one search the DFA
+// gives up and the caller falls back to the NFA.
+//
+// Only programs that don't need backtracking can be turned into a DFA, the
+// only conditional epsilon transitions understood are ^, $, \A and \z. A
+// state remembers whether it's at the start of the input or of a line, the
+// multi-line $ is followed when stepping over a '\n'.
+//
+// The DFA reads bytes while the program reads characters, see dfaChar.
+// ---------------------------------------------
+
+const (
+	// DefaultDFACacheSize is the memory budget of the lazy DFA cache used
+	// when Options.DFACacheSize is 0.
+	DefaultDFACacheSize = 1 << 20
+
+	// approximate cost of one cached state, the transition table dominates
+	dfaStateSize = 256*8 + 64
+
+	// how many times the cache may be flushed during one search before we
+	// decide the DFA thrashes
+	dfaMaxCacheResets = 5
+)
+
+type dfaState struct {
+	pcs []int
+	// which anchors held when the closure was taken
+	at dfaPosition
+	// the character being read
+	char dfaChar
+	// a final instruction is reachable, sticky since we only report
+	// whether there is a match
+	isMatch bool
+	// a final instruction is reachable once $ holds, so it matches if the
+	// input ends here
+	isMatchAtEnd bool
+	next         [256]*dfaState
+}
+
+type lazyDFA struct {
+	prog    *prog
+	cache   map[string]*dfaState
+	size    int
+	maxSize int
+	start   *dfaState
+}
+
+func newLazyDFA(p *prog, maxSize int) *lazyDFA {
+	return &lazyDFA{
+		prog:    p,
+		cache:   make(map[string]*dfaState),
+		maxSize: maxSize,
+	}
+}
+
+// dfaEligible reports whether every matcher of the program can be expressed
+// as a character transition or as a check for the start / end of the input.
+func (p *prog) dfaEligible() bool {
+	if p.needsBacktracking {
+		return false
+	}
+	for _, inst := range p.insts {
+		for _, transition := range inst.transitions {
+			switch m := transition.matcher.(type) {
+			case EpsilonMatcher, StartOfTextMatcher, StartOfStringMatcher, EndOfStringMatcher:
+			case EndOfTextMatcher:
+				if m.beforeFinalNewline {
+					return false
+				}
+			case LiteralMatcher, DigitMatcher, WordMatcher, CharacterGroupMatcher, AnyCharMatcher:
+			default:
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// dfaPosition is what surrounds the position a closure is taken at.
+type dfaPosition struct {
+	atStart bool
+	atEnd   bool
+	// right after / before a '\n', for the multi-line ^ and $
+	afterNewline  bool
+	beforeNewline bool
+}
+
+// closure returns the sorted set of instructions reachable from pcs through
+// epsilon transitions, the anchors are only followed where they hold.
+func (p *prog) closure(pcs []int, at dfaPosition) []int {
+	seen := make([]bool, len(p.insts))
+	stack := slices.Clone(pcs)
+	set := []int{}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[pc] {
+			continue
+		}
+		seen[pc] = true
+		set = append(set, pc)
+
+		for _, transition := range p.insts[pc].transitions {
+			switch m := transition.matcher.(type) {
+			case EpsilonMatcher:
+			case StartOfTextMatcher:
+				if !at.atStart {
+					continue
+				}
+			case StartOfStringMatcher:
+				if !at.atStart && !(m.multiLine && at.afterNewline) {
+					continue
+				}
+			case EndOfTextMatcher:
+				if !at.atEnd {
+					continue
+				}
+			case EndOfStringMatcher:
+				if !at.atEnd && !(m.multiLine && at.beforeNewline) {
+					continue
+				}
+			default:
+				continue
+			}
+			stack = append(stack, transition.to)
+		}
+	}
+	slices.Sort(set)
+
+	return set
+}
+
+// step returns the instructions reached from pcs by consuming c.
+func (p *prog) step(pcs []int, c rune) []int {
+	input := decoder{byteMode: p.byteMode}.encode(c)
+	next := []int{}
+	for _, pc := range pcs {
+		for _, transition := range p.insts[pc].transitions {
+			if transition.matcher.isEpsilon() {
+				continue
+			}
+			if transition.matcher.match(input, 0, Memory{}).match && !slices.Contains(next, transition.to) {
+				next = append(next, transition.to)
+			}
+		}
+	}
+
+	return next
+}
+
+func (p *prog) hasFinal(pcs []int) bool {
+	for _, pc := range pcs {
+		if p.insts[pc].isFinal {
+			return true
+		}
+	}
+	return false
+}
+
+func dfaStateKey(pcs []int, char dfaChar, at dfaPosition) string {
+	var sb strings.Builder
+	if at.atStart {
+		sb.WriteByte('^')
+	}
+	if at.afterNewline {
+		sb.WriteByte('n')
+	}
+	char.writeKey(&sb)
+	for _, pc := range pcs {
+		sb.WriteString(strconv.Itoa(pc))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// state returns the cached DFA state for the closure of pcs, creating it
+// when needed. It returns nil if the cache is over its budget.
+func (d *lazyDFA) state(pcs []int, char dfaChar, at dfaPosition) *dfaState {
+	closure := d.prog.closure(pcs, at)
+	key := dfaStateKey(closure, char, at)
+	if state, ok := d.cache[key]; ok {
+		return state
+	}
+	size := dfaStateSize + 8*(len(closure)+len(char.stepped)) + len(char.partial)
+	if d.size+size > d.maxSize {
+		return nil
+	}
+
+	state := &dfaState{
+		pcs:          closure,
+		at:           at,
+		char:         char,
+		isMatch:      d.prog.hasFinal(closure),
+		isMatchAtEnd: d.prog.matchesAtEnd(closure, char, at),
+	}
+	d.cache[key] = state
+	d.size += size
+
+	return state
+}
+
+func (d *lazyDFA) reset() {
+	d.cache = make(map[string]*dfaState)
+	d.size = 0
+	d.start = nil
+}
+
+// match reports whether input contains a match. ok is false when the cache
+// thrashed and the answer is unknown.
+func (d *lazyDFA) match(input []byte) (matched bool, ok bool) {
+	resets := 0
+	if d.start == nil {
+		d.start = d.state([]int{d.prog.start}, dfaChar{}, dfaPosition{atStart: true})
+		if d.start == nil {
+			return false, false
+		}
+	}
+
+	current := d.start
+	for _, c := range input {
+		if current.isMatch {
+			return true, true
+		}
+
+		next := current.next[c]
+		if next == nil {
+			pcs, char, matched := d.prog.advance(current.pcs, current.char, current.at, c)
+			if matched {
+				return true, true
+			}
+			at := dfaPosition{afterNewline: c == '\n'}
+			next = d.state(pcs, char, at)
+			if next == nil {
+				resets++
+				if resets > dfaMaxCacheResets {
+					return false, false
+				}
+				d.reset()
+				next = d.state(pcs, char, at)
+				if next == nil {
+					return false, false
+				}
+			} else {
+				current.next[c] = next
+			}
+		}
+		current = next
+	}
+
+	return current.isMatch || current.isMatchAtEnd, true
+}
@@ -0,0 +1,221 @@
+package regex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLazyDFAMatchesNFA(t *testing.T) {
+	patterns := []string{"a", "\\d+ apples", "^12", "^12$", "ca{2,4}t", "(cat|dog)s?$", "[^abc]x", "a.b", "^$", "\\A12\\z"}
+	inputs := []string{"", "a", "12", "123", "3 apples", "caaat", "cats", "dogs!", "axb", "dx", "abx"}
+
+	for _, pattern := range patterns {
+		re := MustCompile(pattern)
+		if re.dfaPool == nil {
+			t.Fatalf("Expected a DFA for pattern %v", pattern)
+		}
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("Checking input %v, for pattern %v", input, pattern), func(t *testing.T) {
+				dfa := newLazyDFA(re.prog, DefaultDFACacheSize)
+				matched, ok := dfa.match([]byte(input))
+				expected := re.prog.search([]byte(input), 0, false) != nil
+
+				if !ok || matched != expected {
+					t.Errorf("Expected DFA to report %v, got: %v (ok: %v)", expected, matched, ok)
+				}
+			})
+		}
+	}
+}
+
+func TestDFAUTF8(t *testing.T) {
+	patterns := []string{".", "^.$", "^..$", "[ą-ż]", "[^a]x", "ł.d", "\\W", "日本", "[\\x{800}-\\x{FFFF}]$", "a.$"}
+	inputs := []string{"", "ą", "łód", "ł\xffd", "日本語", "\xff", "a\xe2x", "a\xe2\x82", "\xe2\x82x", "zażółć", "\xf0\x9f\x98\x80"}
+
+	for _, pattern := range patterns {
+		re := MustCompile(pattern)
+		dfa, err := re.DFA()
+		if err != nil {
+			t.Fatalf("Unexpected error building DFA for %v: %v", pattern, err)
+		}
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("Checking input %q, for pattern %v", input, pattern), func(t *testing.T) {
+				expected := re.prog.search([]byte(input), 0, false) != nil
+				matched, ok := newLazyDFA(re.prog, DefaultDFACacheSize).match([]byte(input))
+
+				if !ok || matched != expected {
+					t.Errorf("Expected lazy DFA to report %v, got: %v (ok: %v)", expected, matched, ok)
+				}
+				if dfa.Match([]byte(input)) != expected {
+					t.Errorf("Expected full DFA to report %v", expected)
+				}
+			})
+		}
+	}
+}
+
+func TestDFAMultiLine(t *testing.T) {
+	patterns := []string{"(?m)^a", "(?m)a$", "(?m)^$", "(?m)^a$", "(?m)a$\n^b", "(?m)^.*ą$", "(?m:^b)|^a"}
+	inputs := []string{"", "a", "b\na", "a\nb", "\n", "\n\n", "ba\n", "x\na\nb", "xą\ny", "a\xe2\nb"}
+
+	for _, pattern := range patterns {
+		re := MustCompile(pattern)
+		dfa, err := re.DFA()
+		if err != nil {
+			t.Fatalf("Unexpected error building DFA for %v: %v", pattern, err)
+		}
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("Checking input %q, for pattern %v", input, pattern), func(t *testing.T) {
+				expected := re.prog.search([]byte(input), 0, false) != nil
+				matched, ok := newLazyDFA(re.prog, DefaultDFACacheSize).match([]byte(input))
+
+				if !ok || matched != expected {
+					t.Errorf("Expected lazy DFA to report %v, got: %v (ok: %v)", expected, matched, ok)
+				}
+				if dfa.Match([]byte(input)) != expected {
+					t.Errorf("Expected full DFA to report %v", expected)
+				}
+			})
+		}
+	}
+}
+
+func TestLazyDFANotUsedForBackreferences(t *testing.T) {
+	re := MustCompile("(cat) and \\1")
+
+	if re.dfaPool != nil {
+		t.Errorf("Expected no DFA for a pattern with backreferences")
+	}
+	if !re.MatchString("cat and cat") || re.MatchString("cat and dog") {
+		t.Errorf("Expected backreference pattern to still match through the NFA")
+	}
+}
+
+func TestLazyDFAFallsBackWhenCacheThrashes(t *testing.T) {
+	// every distinct prefix of the alphabet ends up in its own state, a
+	// budget of two states can't hold them
+	pattern := "[a-z]{1,8}0"
+	input := strings.Repeat("abcdefgh", 100)
+
+	dfa := newLazyDFA(MustCompile(pattern).prog, 2*dfaStateSize)
+	if _, ok := dfa.match([]byte(input)); ok {
+		t.Errorf("Expected the DFA to give up when its cache thrashes")
+	}
+
+	re, err := CompileWithOptions(pattern, Options{DFACacheSize: 2 * dfaStateSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.MatchString(input) {
+		t.Errorf("Expected no match for %v", pattern)
+	}
+	if !re.MatchString(input + "0") {
+		t.Errorf("Expected the NFA fallback to find the match")
+	}
+}
+
+func TestLazyDFADisabled(t *testing.T) {
+	re, err := CompileWithOptions("a+", Options{DFACacheSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if re.dfaPool != nil {
+		t.Errorf("Expected a negative cache size to disable the DFA")
+	}
+	if !re.MatchString("baa") {
+		t.Errorf("Expected the NFA to match")
+	}
+}
+
+func TestFullDFAMatchesLazyDFA(t *testing.T) {
+	patterns := []string{"a", "\\d+ apples", "^12", "^12$", "ca{2,4}t", "(cat|dog)s?$", "[^abc]x", "a.b", "^$"}
+	inputs := []string{"", "a", "12", "123", "3 apples", "caaat", "cats", "dogs!", "axb", "dx", "abx"}
+
+	for _, pattern := range patterns {
+		dfa, err := CompileDFA(pattern)
+		if err != nil {
+			t.Fatalf("Unexpected error building DFA for %v: %v", pattern, err)
+		}
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("Checking input %v, for pattern %v", input, pattern), func(t *testing.T) {
+				expected, _ := newLazyDFA(MustCompile(pattern).prog, DefaultDFACacheSize).match([]byte(input))
+
+				if dfa.Match([]byte(input)) != expected {
+					t.Errorf("Expected DFA to report %v", expected)
+				}
+			})
+		}
+	}
+}
+
+func TestFullDFAMinimisation(t *testing.T) {
+	data := []struct {
+		pattern   string
+		numStates int
+	}{
+		// start, match
+		{pattern: "a", numStates: 2},
+		{pattern: "a|a", numStates: 2},
+		{pattern: "(a|a)+", numStates: 2},
+		// start, after a, match
+		{pattern: "ab", numStates: 3},
+		{pattern: "ab|ab", numStates: 3},
+		// start, dead (not at the start anymore), match
+		{pattern: "^a", numStates: 3},
+		// anywhere before the a, just after an a which matches if the input ends
+		{pattern: "a$", numStates: 2},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			dfa, err := CompileDFA(item.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if dfa.NumStates() != item.numStates {
+				t.Errorf("Expected %v states, got: %v", item.numStates, dfa.NumStates())
+			}
+		})
+	}
+}
+
+func TestFullDFAJSONRoundTrip(t *testing.T) {
+	dfa, err := CompileDFA("(cat|dog)s? \\d+$")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := dfa.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &DFA{}
+	if err := loaded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.String() != dfa.String() || loaded.NumStates() != dfa.NumStates() {
+		t.Errorf("Expected loaded DFA %v with %v states, got: %v with %v", dfa, dfa.NumStates(), loaded, loaded.NumStates())
+	}
+	for _, input := range []string{"cats 12", "dog 1", "cow 12", "dogs 12!"} {
+		if loaded.Match([]byte(input)) != dfa.Match([]byte(input)) {
+			t.Errorf("Expected loaded DFA to agree on %v", input)
+		}
+	}
+
+	if err := loaded.UnmarshalJSON([]byte(`{"states":[{"transitions":[{"from":0,"to":10,"next":0}]}]}`)); err == nil {
+		t.Errorf("Expected an error for a state that doesn't cover every byte")
+	}
+}
+
+func TestFullDFAErrors(t *testing.T) {
+	if _, err := CompileDFA("(cat) \\1"); err == nil {
+		t.Errorf("Expected an error for a pattern with backreferences")
+	}
+	if _, err := CompileDFA("(a|b)*a(a|b){14}"); err == nil {
+		t.Errorf("Expected an error when the DFA has too many states")
+	}
+}
@@ -0,0 +1,234 @@
+package regex
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ------------------ UTF-8 in the DFA ------------------
+// The DFA reads bytes while the program reads characters, so a DFA state
+// also holds the leading bytes of the character it's in the middle of. Once
+// the character is complete the program steps over it, and bytes that can't
+// complete a valid UTF-8 sequence are read as utf8.RuneError one at a time,
+// like the NFA does.
+//
+// Keeping the bytes themselves would give every prefix of every multi-byte
+// character a state of its own. Most of the time the matchers can't tell
+// apart the characters a prefix may still become, e.g. [a-z] rejects all of
+// them, so the step is taken right away and only the shape of the prefix
+// (how many bytes are left, which ones are valid) is kept.
+// ---------------------------------------------
+
+// dfaChar is the character a DFA state is in the middle of, the zero value
+// being between two characters.
+type dfaChar struct {
+	// the bytes read so far, only their shape once uniform
+	partial []byte
+	// the character leads to stepped whatever it turns out to be
+	uniform bool
+	stepped []int
+}
+
+func (char dfaChar) writeKey(sb *strings.Builder) {
+	// partial bytes are never ASCII, they can't be confused with the rest
+	sb.Write(char.partial)
+	if char.uniform {
+		sb.WriteByte('=')
+		for _, pc := range char.stepped {
+			sb.WriteString(strconv.Itoa(pc))
+			sb.WriteByte(',')
+		}
+	}
+	sb.WriteByte(':')
+}
+
+// advance reads the byte c from the closure pcs while in the middle of char.
+// It returns the instructions reached, to be closed again, and the character
+// still incomplete. matched is set when a final instruction is reached on
+// the way, which can happen when c ends an invalid sequence whose bytes are
+// then read one by one.
+func (p *prog) advance(pcs []int, char dfaChar, at dfaPosition, c byte) (next []int, nextChar dfaChar, matched bool) {
+	if p.byteMode {
+		if pcs, matched = p.beforeChar(pcs, at, rune(c)); matched {
+			return nil, dfaChar{}, true
+		}
+		return append(p.step(pcs, rune(c)), p.start), dfaChar{}, false
+	}
+
+	pending := append(slices.Clone(char.partial), c)
+	for {
+		if !utf8.FullRune(pending) {
+			return pcs, p.readingChar(pcs, char, pending), false
+		}
+		r, width := utf8.DecodeRune(pending)
+		if pcs, matched = p.beforeChar(pcs, at, r); matched {
+			return nil, dfaChar{}, true
+		}
+		if char.uniform && width == len(pending) {
+			next = append(slices.Clone(char.stepped), p.start)
+		} else {
+			next = append(p.step(pcs, r), p.start)
+		}
+		pending = pending[width:]
+		if len(pending) == 0 {
+			return next, dfaChar{}, false
+		}
+		char = dfaChar{}
+		at = dfaPosition{afterNewline: r == '\n'}
+		pcs = p.closure(next, at)
+		if p.hasFinal(pcs) {
+			return nil, dfaChar{}, true
+		}
+	}
+}
+
+// beforeChar extends the closure pcs, taken at, with the multi-line $ when
+// the character c read next is a '\n'. matched reports a final instruction
+// reached that way.
+func (p *prog) beforeChar(pcs []int, at dfaPosition, c rune) ([]int, bool) {
+	if c != '\n' {
+		return pcs, false
+	}
+	at.beforeNewline = true
+	pcs = p.closure(pcs, at)
+
+	return pcs, p.hasFinal(pcs)
+}
+
+// readingChar returns the character being read from pcs once pending, the
+// leading bytes of a valid UTF-8 sequence, have been read.
+func (p *prog) readingChar(pcs []int, char dfaChar, pending []byte) dfaChar {
+	if char.uniform {
+		return dfaChar{partial: utf8Shape(pending), uniform: true, stepped: char.stepped}
+	}
+
+	lo, hi := utf8Bounds(pending)
+	for _, pc := range pcs {
+		for _, transition := range p.insts[pc].transitions {
+			if !transition.matcher.isEpsilon() && !sameOn(transition.matcher, lo, hi) {
+				return dfaChar{partial: pending}
+			}
+		}
+	}
+	stepped := p.step(pcs, lo)
+	slices.Sort(stepped)
+
+	return dfaChar{partial: utf8Shape(pending), uniform: true, stepped: stepped}
+}
+
+// matchesAtEnd reports whether the closure pcs matches if the input ends
+// here, the bytes of an incomplete character being read as utf8.RuneError.
+func (p *prog) matchesAtEnd(pcs []int, char dfaChar, at dfaPosition) bool {
+	for range char.partial {
+		at = dfaPosition{}
+		pcs = p.closure(append(p.step(pcs, utf8.RuneError), p.start), at)
+		if p.hasFinal(pcs) {
+			return true
+		}
+	}
+	at.atEnd = true
+
+	return p.hasFinal(p.closure(pcs, at))
+}
+
+// sameOn reports whether m gives the same answer for every rune in
+// [lo, hi].
+func sameOn(m Matcher, lo rune, hi rune) bool {
+	switch m := m.(type) {
+	case LiteralMatcher:
+		return m.char < lo || m.char > hi
+	case DigitMatcher:
+		return rangesSameOn(m.ranges, nil, lo, hi)
+	case WordMatcher:
+		return rangesSameOn(m.ranges, m.chars, lo, hi)
+	case CharacterGroupMatcher:
+		return rangesSameOn(m.ranges, m.chars, lo, hi)
+	case AnyCharMatcher:
+		return m.matchNewline || '\n' < lo || '\n' > hi
+	}
+
+	return false
+}
+
+func rangesSameOn(ranges []CharRange, chars []rune, lo rune, hi rune) bool {
+	overlaps := false
+	for _, r := range ranges {
+		if r.from <= lo && r.to >= hi {
+			return true
+		}
+		overlaps = overlaps || (r.from <= hi && r.to >= lo)
+	}
+	for _, c := range chars {
+		overlaps = overlaps || (c >= lo && c <= hi)
+	}
+
+	return !overlaps
+}
+
+// utf8Bounds returns the smallest and largest runes whose encoding starts
+// with the valid, incomplete sequence b.
+func utf8Bounds(b []byte) (rune, rune) {
+	length := 4
+	if b[0] < 0xE0 {
+		length = 2
+	} else if b[0] < 0xF0 {
+		length = 3
+	}
+	lo := slices.Clone(b)
+	hi := slices.Clone(b)
+	for i := len(b); i < length; i++ {
+		loByte, hiByte := byte(0x80), byte(0xBF)
+		if i == 1 {
+			loByte, hiByte = utf8SecondByte(b[0])
+		}
+		lo = append(lo, loByte)
+		hi = append(hi, hiByte)
+	}
+	loRune, _ := utf8.DecodeRune(lo)
+	hiRune, _ := utf8.DecodeRune(hi)
+
+	return loRune, hiRune
+}
+
+// utf8SecondByte returns the range of the byte following lead, the others
+// are in 0x80-0xBF.
+func utf8SecondByte(lead byte) (byte, byte) {
+	switch lead {
+	case 0xE0:
+		return 0xA0, 0xBF
+	case 0xED:
+		return 0x80, 0x9F
+	case 0xF0:
+		return 0x90, 0xBF
+	case 0xF4:
+		return 0x80, 0x8F
+	}
+
+	return 0x80, 0xBF
+}
+
+// utf8Shape returns a sequence which accepts the same bytes after it as the
+// valid, incomplete sequence b.
+func utf8Shape(b []byte) []byte {
+	lead := b[0]
+	switch {
+	case lead < 0xE0:
+		lead = 0xC2
+	case lead != 0xE0 && lead != 0xED && lead < 0xF0:
+		lead = 0xE1
+	case lead > 0xF0 && lead < 0xF4:
+		lead = 0xF1
+	}
+	shape := []byte{lead}
+	for i := 1; i < len(b); i++ {
+		next, _ := utf8SecondByte(lead)
+		if i > 1 {
+			next = 0x80
+		}
+		shape = append(shape, next)
+	}
+
+	return shape
+}
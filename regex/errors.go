@@ -0,0 +1,108 @@
+package regex
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ------------------ Syntax errors ------------------
+// Compile reports a malformed pattern with a *SyntaxError. It tells what's
+// wrong with a stable ErrorKind, which callers can compare against, and
+// where: the byte offset and the text of the offending fragment, e.g.
+//
+//	invalid repeat count at offset 1: `{3,1}`
+//
+// Caret renders the pattern with the fragment underlined for the CLI and
+// the web UI.
+// ---------------------------------------------
+
+// ErrorKind is the reason a pattern doesn't parse. The values don't change,
+// they can be compared against or shown as they are.
+type ErrorKind string
+
+const (
+	ErrInvalidUTF8           ErrorKind = "invalid UTF-8"
+	ErrTrailingBackslash     ErrorKind = "trailing backslash at end of expression"
+	ErrInvalidEscape         ErrorKind = "invalid escape sequence"
+	ErrMissingBracket        ErrorKind = "missing closing ]"
+	ErrInvalidCharRange      ErrorKind = "invalid character class range"
+	ErrInvalidCharClass      ErrorKind = "invalid character class"
+	ErrUnsupportedCharClass  ErrorKind = "unsupported character class"
+	ErrMissingParen          ErrorKind = "missing closing )"
+	ErrUnexpectedParen       ErrorKind = "unexpected )"
+	ErrInvalidNamedCapture   ErrorKind = "invalid named capture"
+	ErrInvalidBackreference  ErrorKind = "invalid backreference"
+	ErrInvalidFlags          ErrorKind = "invalid or unsupported flags"
+	ErrMissingRepeatArgument ErrorKind = "missing argument to repetition operator"
+	ErrInvalidNestedRepeat   ErrorKind = "invalid nested repetition operator"
+	ErrInvalidRepeatSize     ErrorKind = "invalid repeat count"
+	ErrExpressionTooLarge    ErrorKind = "expression too large"
+)
+
+type SyntaxError struct {
+	Kind ErrorKind
+	// the whole pattern, Fragment is Pattern[Offset:Offset+len(Fragment)]
+	Pattern  string
+	Offset   int
+	Fragment string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%v at offset %v: `%v`", e.Kind, e.Offset, e.Fragment)
+}
+
+// Caret returns the line of the pattern holding the fragment and, below it,
+// a '^' under the fragment's first character followed by a '~' for each of
+// the others.
+func (e *SyntaxError) Caret() string {
+	lineStart := strings.LastIndexByte(e.Pattern[:e.Offset], '\n') + 1
+	lineEnd := len(e.Pattern)
+	if end := strings.IndexByte(e.Pattern[e.Offset:], '\n'); end >= 0 {
+		lineEnd = e.Offset + end
+	}
+
+	var sb strings.Builder
+	sb.WriteString(e.Pattern[lineStart:lineEnd])
+	sb.WriteByte('\n')
+	// tabs are kept so the caret lines up with what's above it
+	for _, c := range e.Pattern[lineStart:e.Offset] {
+		if c == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	fragment, _, _ := strings.Cut(e.Fragment, "\n")
+	width := utf8.RuneCountInString(fragment)
+	sb.WriteString(strings.Repeat("~", max(width-1, 0)))
+
+	return sb.String()
+}
+
+// syntaxError returns a kind error for pattern[start:end].
+func syntaxError(pattern string, kind ErrorKind, start int, end int) *SyntaxError {
+	end = min(end, len(pattern))
+	return &SyntaxError{Kind: kind, Pattern: pattern, Offset: start, Fragment: pattern[start:end]}
+}
+
+// errorAt returns a kind error for p.pattern[start:end].
+func (p *Parser) errorAt(kind ErrorKind, start int, end int) error {
+	return syntaxError(p.pattern, kind, start, end)
+}
+
+// invalidUTF8Error reports the first byte of pattern which isn't part of a
+// valid UTF-8 sequence.
+func invalidUTF8Error(pattern string) *SyntaxError {
+	offset := 0
+	for offset < len(pattern) {
+		c, width := utf8.DecodeRuneInString(pattern[offset:])
+		if c == utf8.RuneError && width == 1 {
+			break
+		}
+		offset += width
+	}
+
+	return syntaxError(pattern, ErrInvalidUTF8, offset, offset+1)
+}
@@ -0,0 +1,178 @@
+package regex
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ------------------ Escapes ------------------
+// Escapes understood both on their own and inside brackets:
+//
+//   - \d \w \s and their negations \D \W \S stand for a set of characters.
+//   - \p{Name} and \P{Name} stand for a Unicode class, see unicode.go.
+//   - \t \n \r \f \v, \xHH, \x{HHHH} and octal \0oo stand for one character,
+//     inside brackets \ooo doesn't need the leading 0.
+//   - \Q...\E quotes everything up to \E (or the end of the pattern).
+//   - any other escaped punctuation is the punctuation itself.
+//
+// Anything else, e.g. \y, is an error rather than a literal y, so that
+// escapes added later can't change what an existing pattern means.
+// ---------------------------------------------
+
+var (
+	spaceMatcherRanges = []CharRange{{from: '\t', to: '\n'}, {from: '\f', to: '\r'}, {from: ' ', to: ' '}}
+)
+
+// classEscape returns the characters matched by \d \w \s \D \W \S, esc being
+// the letter after the backslash.
+func classEscape(esc byte) ([]CharRange, bool) {
+	switch esc {
+	case 'd':
+		return digitMatcherRanges, true
+	case 'w':
+		return wordRanges(), true
+	case 's':
+		return spaceMatcherRanges, true
+	case 'D':
+		return negateRanges(digitMatcherRanges), true
+	case 'W':
+		return negateRanges(wordRanges()), true
+	case 'S':
+		return negateRanges(spaceMatcherRanges), true
+	}
+
+	return nil, false
+}
+
+// escapeClass is classEscape once the flags are applied: when case
+// insensitive the set is folded, before being negated for \D \W \S.
+func (p *Parser) escapeClass(esc byte) ([]CharRange, bool) {
+	if !p.flags.caseInsensitive {
+		return classEscape(esc)
+	}
+	positive := esc | 0x20
+	ranges, ok := classEscape(positive)
+	if !ok {
+		return nil, false
+	}
+	ranges = p.foldRanges(ranges)
+	if esc != positive {
+		return negateRanges(ranges), true
+	}
+
+	return ranges, true
+}
+
+func wordRanges() []CharRange {
+	ranges := append([]CharRange{}, wordMarcherRanges...)
+	for _, c := range wordMatcherChars {
+		ranges = append(ranges, CharRange{from: c, to: c})
+	}
+
+	return ranges
+}
+
+// negateRanges returns the characters none of the ranges contain.
+func negateRanges(ranges []CharRange) []CharRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b CharRange) int {
+		return cmp.Compare(a.from, b.from)
+	})
+
+	negated := []CharRange{}
+	next := rune(0)
+	for _, r := range sorted {
+		if r.from > next {
+			negated = append(negated, CharRange{from: next, to: r.from - 1})
+		}
+		next = max(next, r.to+1)
+	}
+	if next <= unicode.MaxRune {
+		negated = append(negated, CharRange{from: next, to: unicode.MaxRune})
+	}
+
+	return negated
+}
+
+// parseEscapeChar decodes an escape standing for a single character, esc is
+// the character after the backslash and p.pos points right after it. ok is
+// false when esc doesn't start such an escape.
+func (p *Parser) parseEscapeChar(esc byte, inClass bool) (c rune, ok bool, err error) {
+	switch esc {
+	case 't':
+		return '\t', true, nil
+	case 'n':
+		return '\n', true, nil
+	case 'r':
+		return '\r', true, nil
+	case 'f':
+		return '\f', true, nil
+	case 'v':
+		return '\v', true, nil
+	case 'x':
+		return p.parseHexEscape()
+	}
+
+	if esc == '0' || (inClass && esc >= '1' && esc <= '7') {
+		// up to three octal digits including esc
+		digits := string(esc)
+		for len(digits) < 3 && !p.isEnd() && p.pattern[p.pos] >= '0' && p.pattern[p.pos] <= '7' {
+			digits += string(p.pattern[p.pos])
+			p.pos++
+		}
+		value, _ := strconv.ParseUint(digits, 8, 32)
+		return rune(value), true, nil
+	}
+
+	if esc < 0x80 && !isWordChar(esc) {
+		return rune(esc), true, nil
+	}
+
+	return 0, false, nil
+}
+
+// parseHexEscape parses what follows \x: two hex digits or any number of
+// them in braces.
+func (p *Parser) parseHexEscape() (rune, bool, error) {
+	start := p.pos - 2
+	digits := ""
+	if !p.isEnd() && p.pattern[p.pos] == '{' {
+		end := strings.IndexByte(p.pattern[p.pos:], '}')
+		if end < 0 {
+			return 0, true, p.errorAt(ErrInvalidEscape, start, len(p.pattern))
+		}
+		digits = p.pattern[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+	} else if p.pos+2 <= len(p.pattern) {
+		digits = p.pattern[p.pos : p.pos+2]
+		p.pos += 2
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || value > 0x10FFFF {
+		return 0, true, p.errorAt(ErrInvalidEscape, start, p.pos)
+	}
+
+	return rune(value), true, nil
+}
+
+// escapeError reports the escape whose backslash is at start as invalid.
+func (p *Parser) escapeError(start int) error {
+	_, width := utf8.DecodeRuneInString(p.pattern[start+1:])
+	return p.errorAt(ErrInvalidEscape, start, start+1+width)
+}
+
+// quoteEnd returns the position right after the \E closing the quote whose
+// text starts at pos, len(pattern) when it's never closed.
+func quoteEnd(pattern string, pos int) int {
+	end := strings.Index(pattern[pos:], `\E`)
+	if end < 0 {
+		return len(pattern)
+	}
+
+	return pos + end + 2
+}
@@ -0,0 +1,180 @@
+package regex
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ------------------ Flags ------------------
+// Flags change how the rest of the pattern is parsed. They're set for the
+// whole pattern by Options, or inline:
+//
+//   - (?flags) sets them up to the end of the enclosing group.
+//   - (?flags:re) sets them for re only.
+//   - flags after a '-' are cleared, e.g. (?i-s) or (?-i).
+//
+// With i (case insensitive) every character matches all the characters it
+// folds to (unicode.SimpleFold): literals and sets are widened to those at
+// parse time, and backreferences compare the characters folded. Negated sets
+// are folded before being negated, so (?i)[^k] matches neither k nor K.
+// ---------------------------------------------
+
+// the characters folding to another one are in [minFold, maxFold]
+const (
+	minFold = 'A'
+	maxFold = 0x1E943
+)
+
+type flags struct {
+	// i: letters match regardless of their case
+	caseInsensitive bool
+	// m: ^ and $ also match right after / before a '\n'
+	multiLine bool
+	// s: . also matches '\n'
+	dotNewline bool
+	// U: quantifiers are lazy, followed by '?' they're greedy
+	ungreedy bool
+}
+
+// parseFlags parses the flags of (?flags) or (?flags:re), p.pos being right
+// after "(?". It returns the flags once applied to the current ones and
+// whether re follows, p.pos being after the ':' or the ')'.
+func (p *Parser) parseFlags() (flags, bool, error) {
+	f := p.flags
+	start := p.pos - 2
+	set := true
+	sawFlag := false
+	for !p.isEnd() {
+		c := p.pattern[p.pos]
+		p.pos++
+		switch c {
+		case 'i':
+			f.caseInsensitive = set
+		case 'm':
+			f.multiLine = set
+		case 's':
+			f.dotNewline = set
+		case 'U':
+			f.ungreedy = set
+		case '-':
+			if !set || p.isEnd() || p.pattern[p.pos] == ')' || p.pattern[p.pos] == ':' {
+				return flags{}, false, p.errorAt(ErrInvalidFlags, start, p.pos)
+			}
+			set = false
+			continue
+		case ':', ')':
+			if !sawFlag {
+				return flags{}, false, p.errorAt(ErrInvalidFlags, start, p.pos)
+			}
+			return f, c == ':', nil
+		default:
+			_, width := utf8.DecodeRuneInString(p.pattern[p.pos-1:])
+			return flags{}, false, p.errorAt(ErrInvalidFlags, start, p.pos-1+width)
+		}
+		sawFlag = true
+	}
+
+	return flags{}, false, p.errorAt(ErrMissingParen, start, len(p.pattern))
+}
+
+// isFlagGroup reports whether the group opened at pos sets flags, any (?
+// that doesn't start another kind of group does.
+func isFlagGroup(pattern string, pos int) bool {
+	rest := pattern[pos+1:]
+	return len(rest) > 1 && rest[0] == '?' && strings.IndexByte(":>=!<P", rest[1]) < 0
+}
+
+// foldRanges returns ranges with every character the characters they
+// contain fold to. In byte mode only ASCII letters are folded.
+func (p *Parser) foldRanges(ranges []CharRange) []CharRange {
+	last := rune(maxFold)
+	if p.decoder.byteMode {
+		last = utf8.RuneSelf - 1
+	}
+
+	folded := slices.Clone(ranges)
+	for _, r := range ranges {
+		if r.from <= minFold && r.to >= maxFold {
+			// already holds every character that folds
+			continue
+		}
+		for c := max(r.from, minFold); c <= min(r.to, last); c++ {
+			for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+				if f <= last {
+					folded = append(folded, CharRange{from: f, to: f})
+				}
+			}
+		}
+	}
+
+	return mergeRanges(folded)
+}
+
+// foldOrbit returns c and every character it folds to, in byte mode only
+// ASCII letters are folded.
+func (d decoder) foldOrbit(c rune) []rune {
+	orbit := []rune{c}
+	if d.byteMode && c >= utf8.RuneSelf {
+		return orbit
+	}
+	for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+		if !d.byteMode || f < utf8.RuneSelf {
+			orbit = append(orbit, f)
+		}
+	}
+
+	return orbit
+}
+
+// foldKey returns the smallest character of c's fold orbit, all the
+// characters of the orbit get the same key.
+func (d decoder) foldKey(c rune) rune {
+	key := c
+	if d.byteMode && c >= utf8.RuneSelf {
+		return key
+	}
+	for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+		if f < key && (!d.byteMode || f < utf8.RuneSelf) {
+			key = f
+		}
+	}
+
+	return key
+}
+
+// equalFold reports whether a and b are the same character once folded.
+func equalFold(a rune, b rune) bool {
+	if a == b {
+		return true
+	}
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeRanges sorts the ranges and merges the ones overlapping or touching.
+func mergeRanges(ranges []CharRange) []CharRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b CharRange) int {
+		return cmp.Compare(a.from, b.from)
+	})
+
+	merged := []CharRange{}
+	for _, r := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && r.from <= merged[last].to+1 {
+			merged[last].to = max(merged[last].to, r.to)
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
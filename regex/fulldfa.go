@@ -0,0 +1,333 @@
+package regex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ------------------ Full DFA ------------------
+// Ahead of time version of the lazy DFA: the whole subset construction is
+// run up front and the result is minimised with Hopcroft's algorithm, so the
+// automaton can be inspected, compared and shipped precompiled (see
+// MarshalJSON).
+//
+// It answers the same question as the lazy DFA, whether the input contains a
+// match, and has the same restrictions (see dfaEligible).
+// ---------------------------------------------
+
+// MaxDFAStates bounds the subset construction, patterns such as (a|b)*a.{20}
+// blow up exponentially and are better served by the lazy DFA.
+const MaxDFAStates = 10000
+
+// DFA is a complete, minimised deterministic automaton. State 0 is the start
+// state and states are numbered in breadth first order from it, so two DFAs
+// built from equivalent patterns are identical.
+type DFA struct {
+	pattern string
+	states  []dfaNode
+}
+
+type dfaNode struct {
+	next         [256]int
+	isMatch      bool
+	isMatchAtEnd bool
+}
+
+// DFA builds the minimised DFA of the pattern.
+func (re *Regexp) DFA() (*DFA, error) {
+	if !re.prog.dfaEligible() {
+		return nil, fmt.Errorf("pattern %v can't be converted to a DFA", re.pattern)
+	}
+
+	nodes, err := re.prog.subsetConstruction()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DFA{pattern: re.pattern, states: minimiseDFA(nodes)}, nil
+}
+
+// CompileDFA parses a pattern and builds its minimised DFA.
+func CompileDFA(pattern string) (*DFA, error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.DFA()
+}
+
+// String returns the source text the DFA was built from.
+func (d *DFA) String() string {
+	return d.pattern
+}
+
+// NumStates returns the number of states of the DFA.
+func (d *DFA) NumStates() int {
+	return len(d.states)
+}
+
+// Match reports whether b contains any match of the pattern.
+func (d *DFA) Match(b []byte) bool {
+	state := 0
+	for _, c := range b {
+		if d.states[state].isMatch {
+			return true
+		}
+		state = d.states[state].next[c]
+	}
+
+	return d.states[state].isMatch || d.states[state].isMatchAtEnd
+}
+
+// subsetConstruction explores every DFA state reachable from the start state,
+// it's the eager counterpart of lazyDFA.state. Match states get a single
+// absorbing successor since a match can't be undone.
+func (p *prog) subsetConstruction() ([]dfaNode, error) {
+	nodes := []dfaNode{}
+	sets := [][]int{}
+	chars := []dfaChar{}
+	positions := []dfaPosition{}
+	index := make(map[string]int)
+
+	addState := func(pcs []int, char dfaChar, at dfaPosition) (int, error) {
+		closure := p.closure(pcs, at)
+		key := dfaStateKey(closure, char, at)
+		if i, ok := index[key]; ok {
+			return i, nil
+		}
+		if len(nodes) >= MaxDFAStates {
+			return 0, fmt.Errorf("DFA has more than %v states", MaxDFAStates)
+		}
+		index[key] = len(nodes)
+		nodes = append(nodes, dfaNode{
+			isMatch:      p.hasFinal(closure),
+			isMatchAtEnd: p.matchesAtEnd(closure, char, at),
+		})
+		sets = append(sets, closure)
+		chars = append(chars, char)
+		positions = append(positions, at)
+		return len(nodes) - 1, nil
+	}
+
+	if _, err := addState([]int{p.start}, dfaChar{}, dfaPosition{atStart: true}); err != nil {
+		return nil, err
+	}
+	// reached when an invalid sequence completes a match halfway through
+	// a byte, see advance
+	matchState := -1
+
+	for i := 0; i < len(nodes); i++ {
+		if nodes[i].isMatch {
+			for c := range nodes[i].next {
+				nodes[i].next[c] = i
+			}
+			continue
+		}
+		for c := 0; c < 256; c++ {
+			pcs, char, matched := p.advance(sets[i], chars[i], positions[i], byte(c))
+			if matched {
+				if matchState < 0 {
+					matchState = len(nodes)
+					nodes = append(nodes, dfaNode{isMatch: true})
+					sets = append(sets, nil)
+					chars = append(chars, dfaChar{})
+					positions = append(positions, dfaPosition{})
+				}
+				nodes[i].next[c] = matchState
+				continue
+			}
+			next, err := addState(pcs, char, dfaPosition{afterNewline: c == '\n'})
+			if err != nil {
+				return nil, err
+			}
+			nodes[i].next[c] = next
+		}
+	}
+
+	return nodes, nil
+}
+
+// minimiseDFA merges equivalent states with Hopcroft's partition refinement:
+// start from {match}, {match at end}, {rest} and keep splitting blocks whose
+// states disagree on which block a byte leads to.
+func minimiseDFA(nodes []dfaNode) []dfaNode {
+	blockOf := make([]int, len(nodes))
+	blocks := [][]int{}
+	initial := make(map[[2]bool]int)
+	for s, node := range nodes {
+		key := [2]bool{node.isMatch, node.isMatchAtEnd}
+		b, ok := initial[key]
+		if !ok {
+			b = len(blocks)
+			initial[key] = b
+			blocks = append(blocks, []int{})
+		}
+		blockOf[s] = b
+		blocks[b] = append(blocks[b], s)
+	}
+
+	// inverse[c][t] lists the states moving to t on c
+	inverse := make([][][]int, 256)
+	for c := range inverse {
+		inverse[c] = make([][]int, len(nodes))
+	}
+	for s, node := range nodes {
+		for c, t := range node.next {
+			inverse[c][t] = append(inverse[c][t], s)
+		}
+	}
+
+	work := []int{}
+	inWork := []bool{}
+	for b := range blocks {
+		work = append(work, b)
+		inWork = append(inWork, true)
+	}
+
+	for len(work) > 0 {
+		splitter := work[len(work)-1]
+		work = work[:len(work)-1]
+		inWork[splitter] = false
+		target := blocks[splitter]
+
+		for c := 0; c < 256; c++ {
+			// states that move into the splitter on c, grouped by block
+			hits := make(map[int][]int)
+			for _, t := range target {
+				for _, s := range inverse[c][t] {
+					hits[blockOf[s]] = append(hits[blockOf[s]], s)
+				}
+			}
+
+			for b, inside := range hits {
+				if len(inside) == len(blocks[b]) {
+					continue
+				}
+				isInside := make(map[int]bool, len(inside))
+				for _, s := range inside {
+					isInside[s] = true
+				}
+				outside := []int{}
+				for _, s := range blocks[b] {
+					if !isInside[s] {
+						outside = append(outside, s)
+					}
+				}
+
+				newBlock := len(blocks)
+				blocks[b] = outside
+				blocks = append(blocks, inside)
+				for _, s := range inside {
+					blockOf[s] = newBlock
+				}
+
+				if inWork[b] || len(inside) <= len(outside) {
+					work = append(work, newBlock)
+					inWork = append(inWork, true)
+				} else {
+					work = append(work, b)
+					inWork[b] = true
+					inWork = append(inWork, false)
+				}
+			}
+		}
+	}
+
+	// renumber blocks breadth first from the start state
+	order := map[int]int{blockOf[0]: 0}
+	queue := []int{blockOf[0]}
+	minimised := []dfaNode{}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		representative := nodes[blocks[b][0]]
+		node := dfaNode{isMatch: representative.isMatch, isMatchAtEnd: representative.isMatchAtEnd}
+		for c, t := range representative.next {
+			next, ok := order[blockOf[t]]
+			if !ok {
+				next = len(order)
+				order[blockOf[t]] = next
+				queue = append(queue, blockOf[t])
+			}
+			node.next[c] = next
+		}
+		minimised = append(minimised, node)
+	}
+
+	return minimised
+}
+
+type dfaJson struct {
+	Pattern string         `json:"pattern"`
+	States  []dfaStateJson `json:"states"`
+}
+
+type dfaStateJson struct {
+	IsMatch      bool           `json:"isMatch,omitempty"`
+	IsMatchAtEnd bool           `json:"isMatchAtEnd,omitempty"`
+	Transitions  []dfaRangeJson `json:"transitions"`
+}
+
+// dfaRangeJson sends every byte in [From, To] to state Next.
+type dfaRangeJson struct {
+	From byte `json:"from"`
+	To   byte `json:"to"`
+	Next int  `json:"next"`
+}
+
+// MarshalJSON exports the DFA with transitions compressed into byte ranges.
+func (d *DFA) MarshalJSON() ([]byte, error) {
+	out := dfaJson{Pattern: d.pattern, States: make([]dfaStateJson, len(d.states))}
+	for i, node := range d.states {
+		state := dfaStateJson{IsMatch: node.isMatch, IsMatchAtEnd: node.isMatchAtEnd, Transitions: []dfaRangeJson{}}
+		for c := 0; c < 256; c++ {
+			last := len(state.Transitions) - 1
+			if last >= 0 && state.Transitions[last].Next == node.next[c] && int(state.Transitions[last].To) == c-1 {
+				state.Transitions[last].To = byte(c)
+				continue
+			}
+			state.Transitions = append(state.Transitions, dfaRangeJson{From: byte(c), To: byte(c), Next: node.next[c]})
+		}
+		out.States[i] = state
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON loads a DFA exported by MarshalJSON.
+func (d *DFA) UnmarshalJSON(data []byte) error {
+	in := dfaJson{}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if len(in.States) == 0 {
+		return fmt.Errorf("DFA has no states")
+	}
+
+	states := make([]dfaNode, len(in.States))
+	for i, state := range in.States {
+		states[i].isMatch = state.IsMatch
+		states[i].isMatchAtEnd = state.IsMatchAtEnd
+		covered := [256]bool{}
+		for _, r := range state.Transitions {
+			if r.Next < 0 || r.Next >= len(states) || r.From > r.To {
+				return fmt.Errorf("state %v has an invalid transition", i)
+			}
+			for c := int(r.From); c <= int(r.To); c++ {
+				states[i].next[c] = r.Next
+				covered[c] = true
+			}
+		}
+		for c, ok := range covered {
+			if !ok {
+				return fmt.Errorf("state %v has no transition for byte %v", i, c)
+			}
+		}
+	}
+
+	d.pattern = in.Pattern
+	d.states = states
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package regex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// FuzzCompile checks the parser never panics: any pattern either compiles
+// and can be run, or is rejected with a *SyntaxError.
+func FuzzCompile(f *testing.F) {
+	seeds := []string{
+		"", "a|", "(|a)", "()", "(", ")", "[", "\\", "{", "a{", "a{2,", "a{,2}", "a{3,1}",
+		"*a", "a**", "a{2}{3}", "a*?+", "(a|b)*c", "[a-z]+\\d{1,3}", "[[:alpha:]\\p{Greek}]",
+		"(?i)x(?-i:y)", "(?<n>a)\\k<n>\\1", "(?<=a)b(?!c)", "\\Qa*\\E+", "\\x{10FFFF}", "ą\xff",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, pattern string) {
+		re, err := Compile(pattern)
+		if err != nil {
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a syntax error for %q, got: %v", pattern, err)
+			}
+			syntaxErr.Caret()
+			return
+		}
+		re.FindAllString("abc 123\nąż ab", -1)
+	})
+}
+
+// FuzzParseRoundTrip checks a tree prints as a pattern parsing back to the
+// same tree.
+func FuzzParseRoundTrip(f *testing.F) {
+	seeds := []string{
+		"a|", "(|a)b", "a\\Q\\E*", "(?:a|b)|c", "(a)\\10", "(?i)ab(?-i)c", "(?m)^$", "[\\]\\-^]",
+		"a{2,}?b{3}+", "(?>a)(?<=b)", "\\x{0}\\t\\pL", "(?<n>a)\\k<n>",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, pattern string) {
+		node, err := Parse(pattern)
+		if err != nil {
+			return
+		}
+		printed := node.String()
+		reparsed, err := Parse(printed)
+		if err != nil {
+			t.Fatalf("Error parsing %q, printed from %q: %v", printed, pattern, err)
+		}
+		if !reflect.DeepEqual(node, reparsed) {
+			t.Fatalf("Expected %q printed as %q to parse to the same tree, got %#v and %#v", pattern, printed, node, reparsed)
+		}
+	})
+}
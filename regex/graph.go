@@ -0,0 +1,106 @@
+package regex
+
+import "strings"
+
+// Graph is a read-only snapshot of the compiled program, it's used by the web
+// visualiser to draw the state diagram.
+type Graph struct {
+	InitState string
+	States    []GraphState
+}
+
+type GraphState struct {
+	Name        string
+	Transitions []GraphTransition
+	IsFinal     bool
+	// Groups marks the capture groups the state opens "(1" and closes "1)",
+	// empty when it does neither
+	Groups string
+}
+
+type GraphTransition struct {
+	To        string
+	Label     string
+	IsEpsilon bool
+}
+
+// Graph returns the states and transitions of the compiled program, state i
+// is instruction i.
+func (re *Regexp) Graph() Graph {
+	states := make([]GraphState, len(re.prog.insts))
+
+	for i, state := range re.prog.insts {
+		transitions := make([]GraphTransition, len(state.transitions))
+		for j, trans := range state.transitions {
+			transitions[j] = GraphTransition{
+				To:        stateName(trans.to),
+				Label:     getMatcherLabel(trans.matcher),
+				IsEpsilon: trans.matcher.isEpsilon(),
+			}
+		}
+
+		states[i] = GraphState{
+			Name:        stateName(i),
+			Transitions: transitions,
+			IsFinal:     state.isFinal,
+			Groups:      getGroupLabel(state),
+		}
+	}
+
+	return Graph{
+		InitState: stateName(re.prog.start),
+		States:    states,
+	}
+}
+
+func getGroupLabel(inst instruction) string {
+	labels := []string{}
+	for _, group := range inst.startGroup {
+		labels = append(labels, "("+group)
+	}
+	for _, group := range inst.endGroup {
+		labels = append(labels, group+")")
+	}
+
+	return strings.Join(labels, " ")
+}
+
+func getMatcherLabel(matcher Matcher) string {
+	switch m := matcher.(type) {
+	case AtomicStartMatcher:
+		return "(?>"
+	case AtomicEndMatcher:
+		return ")"
+	case LookaroundMatcher:
+		return m.label
+	case WordBoundaryMatcher:
+		if m.negated {
+			return "\\B"
+		}
+		return "\\b"
+	case StartOfTextMatcher:
+		return "\\A"
+	case EndOfTextMatcher:
+		if m.beforeFinalNewline {
+			return "\\Z"
+		}
+		return "\\z"
+	}
+	if matcher.isEpsilon() {
+		return "ε"
+	}
+	switch m := matcher.(type) {
+	case LiteralMatcher:
+		return string(m.char)
+	case DigitMatcher:
+		return "\\d"
+	case WordMatcher:
+		return "\\w"
+	case CharacterGroupMatcher:
+		return m.label
+	case AnyCharMatcher:
+		return "."
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,146 @@
+package regex
+
+import (
+	"bytes"
+	"slices"
+)
+
+// ------------------ Literals ------------------
+// Most patterns people grep for contain plain text, e.g. "error: \d+". Every
+// match has to contain that text, so:
+//
+//   - prefix is the literal every match starts with, the search jumps
+//     straight to its next occurrence (bytes.Index) instead of starting a
+//     thread at every byte.
+//   - required is the longest literal every match contains, a line without
+//     it is rejected without running any automaton.
+//
+// Both are found on the program: walking from a set of instructions, as long
+// as every consuming transition leaving the set is the same LiteralMatcher
+// the character it reads is forced. Epsilon transitions are all assumed to pass,
+// which only adds paths, so the result stays correct for assertions.
+// ---------------------------------------------
+
+const (
+	// longer literals don't make bytes.Index noticeably faster
+	maxLiteralLen = 64
+
+	// finding required literals costs O(len(insts)^2), skip it for huge
+	// programs
+	maxLiteralAnalysisInsts = 1000
+)
+
+// reachable returns the instructions reachable from pcs through any epsilon
+// transition.
+func (p *prog) reachable(pcs []int) []int {
+	seen := make([]bool, len(p.insts))
+	stack := slices.Clone(pcs)
+	set := []int{}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[pc] {
+			continue
+		}
+		seen[pc] = true
+		set = append(set, pc)
+
+		for _, transition := range p.insts[pc].transitions {
+			if transition.matcher.isEpsilon() {
+				stack = append(stack, transition.to)
+			}
+		}
+	}
+
+	return set
+}
+
+// forcedLiteral returns the bytes every path from pc to a final instruction
+// has to consume first.
+func (p *prog) forcedLiteral(pc int) []byte {
+	literal := []byte{}
+	set := p.reachable([]int{pc})
+	for len(literal) < maxLiteralLen && !p.hasFinal(set) {
+		next := []int{}
+		found := false
+		var char LiteralMatcher
+		for _, pc := range set {
+			for _, transition := range p.insts[pc].transitions {
+				if transition.matcher.isEpsilon() {
+					continue
+				}
+				lm, ok := transition.matcher.(LiteralMatcher)
+				if !ok || (found && lm.char != char.char) {
+					return literal
+				}
+				char = lm
+				found = true
+				next = append(next, transition.to)
+			}
+		}
+		if !found {
+			break
+		}
+		literal = append(literal, char.encode(char.char)...)
+		set = p.reachable(next)
+	}
+
+	return literal
+}
+
+// dominatesFinal reports whether every path from the start instruction to a
+// final one goes through pc.
+func (p *prog) dominatesFinal(pc int) bool {
+	if pc == p.start {
+		return true
+	}
+	seen := make([]bool, len(p.insts))
+	seen[pc] = true
+	stack := []int{p.start}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		if p.insts[current].isFinal {
+			return false
+		}
+		for _, transition := range p.insts[current].transitions {
+			stack = append(stack, transition.to)
+		}
+	}
+
+	return true
+}
+
+// literalPrefix returns the literal every match starts with.
+func (p *prog) literalPrefix() []byte {
+	return p.forcedLiteral(p.start)
+}
+
+// requiredLiteral returns the longest literal every match contains: the one
+// forced right after some instruction all matches have to go through.
+func (p *prog) requiredLiteral() []byte {
+	required := p.literalPrefix()
+	if len(p.insts) > maxLiteralAnalysisInsts {
+		return required
+	}
+	for pc := range p.insts {
+		if pc == p.start || !p.dominatesFinal(pc) {
+			continue
+		}
+		if literal := p.forcedLiteral(pc); len(literal) > len(required) {
+			required = literal
+		}
+	}
+
+	return required
+}
+
+// canMatch reports whether b contains the required literal, if it doesn't
+// there can be no match.
+func (p *prog) canMatch(b []byte) bool {
+	return len(p.required) == 0 || bytes.Contains(b, p.required)
+}
@@ -0,0 +1,142 @@
+package regex
+
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+// ------------------ Lookaround ------------------
+// (?=...), (?!...), (?<=...) and (?<!...) check what comes after or before
+// the current position without consuming it. The sub-pattern is compiled to
+// its own program and the matcher is an epsilon transition which passes when
+// that program matches (or, negated, when it doesn't).
+//
+//   - lookahead runs the program anchored at the current position.
+//   - lookbehind runs the program from every character start the length of
+//     the sub-pattern allows, only a match ending exactly at the current
+//     position counts. Sub-patterns with a bounded length only try a few
+//     starts, unbounded ones try them all. The program sees the whole input,
+//     so $, \b or a lookahead in it also look past the current position.
+//
+// A positive lookaround keeps what the groups inside it captured, like in
+// Perl: (a)(?=(b)) on "ab" gives group 2 the "b". Only the first match of the
+// sub-pattern is looked at, the lookaround doesn't backtrack into it. Groups
+// inside a negative lookaround never capture. Backreferences inside it see
+// the groups captured before it, which makes the whole pattern need
+// backtracking.
+// ---------------------------------------------
+
+type LookaroundMatcher struct {
+	prog     *prog
+	negative bool
+	behind   bool
+	// longest match of the sub-pattern, -1 when unbounded
+	maxLen int
+	// capture slots of the groups inside the sub-pattern
+	slots []int
+	label string
+}
+
+func (lookaroundMatcher LookaroundMatcher) match(line []byte, index int, memory Memory) MatchResult {
+	var caps []int
+	if lookaroundMatcher.behind {
+		from := 0
+		if lookaroundMatcher.maxLen >= 0 {
+			maxBytes := lookaroundMatcher.maxLen
+			if !lookaroundMatcher.prog.byteMode {
+				maxBytes *= utf8.UTFMax
+			}
+			from = max(0, index-maxBytes)
+		}
+		for start := index; start >= from && caps == nil; start-- {
+			if !lookaroundMatcher.prog.byteMode && start < index && !utf8.RuneStart(line[start]) {
+				// don't start in the middle of a character
+				continue
+			}
+			caps = lookaroundMatcher.matchAt(line, start, index, memory)
+		}
+	} else {
+		caps = lookaroundMatcher.matchAt(line, index, -1, memory)
+	}
+
+	if lookaroundMatcher.negative || caps == nil {
+		return MatchResult{match: (caps == nil) == lookaroundMatcher.negative, consume: 0}
+	}
+
+	return MatchResult{match: true, consume: 0, caps: lookaroundMatcher.capture(memory.caps, caps)}
+}
+
+// capture returns the slots of the path, caps, with the groups inside the
+// sub-pattern set to what they captured in its match.
+func (lookaroundMatcher LookaroundMatcher) capture(caps []int, match []int) []int {
+	if len(caps) != len(match) {
+		caps = newCaps(len(match))
+	}
+	caps = slices.Clone(caps)
+	for _, slot := range lookaroundMatcher.slots {
+		caps[slot] = match[slot]
+	}
+
+	return caps
+}
+
+func (lookaroundMatcher LookaroundMatcher) isEpsilon() bool {
+	return true
+}
+
+// matchAt returns the capture slots of the sub-pattern's match starting
+// exactly at index and, unless it's -1, ending exactly at end. nil if there's
+// none.
+func (lookaroundMatcher LookaroundMatcher) matchAt(line []byte, index int, end int, memory Memory) []int {
+	p := lookaroundMatcher.prog
+	if p.hasBackreference && len(memory.caps) == p.numSlots {
+		return p.runWithCaps(line, index, memory.caps, end)
+	}
+
+	return p.matchEndingAt(line, index, end)
+}
+
+// maxLength returns the most characters a match of the program can consume, -1
+// when there's no bound (loops, backreferences).
+func (p *prog) maxLength() int {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(p.insts))
+	longest := make([]int, len(p.insts))
+
+	var visit func(pc int) int
+	visit = func(pc int) int {
+		switch state[pc] {
+		case visiting:
+			return -1
+		case done:
+			return longest[pc]
+		}
+		state[pc] = visiting
+
+		length := 0
+		for _, transition := range p.insts[pc].transitions {
+			step := 0
+			if !transition.matcher.isEpsilon() {
+				if _, ok := transition.matcher.(BackreferenceMatcher); ok {
+					return -1
+				}
+				step = 1
+			}
+			next := visit(transition.to)
+			if next < 0 {
+				return -1
+			}
+			length = max(length, next+step)
+		}
+
+		state[pc] = done
+		longest[pc] = length
+		return length
+	}
+
+	return visit(p.start)
+}
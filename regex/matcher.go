@@ -0,0 +1,361 @@
+package regex
+
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+// ------------------ Matcher ------------------
+// Matchers that read a character decode it with decoder: a UTF-8 encoded
+// rune, or a single byte in byte mode (Options.Bytes). Invalid UTF-8 reads
+// as utf8.RuneError one byte at a time, like the standard library does, and
+// consume is the width of what was read.
+
+type MatchResult struct {
+	match   bool
+	consume int
+	// the capture slots of the path once it went through the matcher, nil
+	// when they don't change. Only lookarounds capture.
+	caps []int
+}
+type Matcher interface {
+	match(b []byte, index int, memory Memory) MatchResult
+	isEpsilon() bool
+}
+
+type EpsilonMatcher struct {
+	char byte
+}
+
+func (lm EpsilonMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	return MatchResult{match: true, consume: 0}
+}
+
+func (lm EpsilonMatcher) isEpsilon() bool {
+	return true
+}
+
+// decoder reads characters from the input, it's embedded in every matcher
+// that consumes one character.
+type decoder struct {
+	byteMode bool
+}
+
+func (d decoder) decode(b []byte, index int) (rune, int) {
+	if d.byteMode || b[index] < utf8.RuneSelf {
+		return rune(b[index]), 1
+	}
+	return utf8.DecodeRune(b[index:])
+}
+
+// decodeString is decode for the pattern.
+func (d decoder) decodeString(s string) (rune, int) {
+	if d.byteMode || s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
+
+// encode returns the bytes decode reads as c.
+func (d decoder) encode(c rune) []byte {
+	if d.byteMode {
+		return []byte{byte(c)}
+	}
+	return utf8.AppendRune(nil, c)
+}
+
+type LiteralMatcher struct {
+	decoder
+	char rune
+}
+
+func (lm LiteralMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	c, width := lm.decode(b, index)
+	return MatchResult{match: lm.char == c, consume: width}
+}
+
+func (lm LiteralMatcher) isEpsilon() bool {
+	return false
+}
+
+type DigitMatcher struct {
+	decoder
+	ranges []CharRange
+}
+
+func (dm DigitMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	c, width := dm.decode(b, index)
+	return MatchResult{match: matchRanges(dm.ranges, c), consume: width}
+}
+
+func (dm DigitMatcher) isEpsilon() bool {
+	return false
+}
+
+func NewDigitMatcher(d decoder) DigitMatcher {
+	return DigitMatcher{
+		decoder: d,
+		ranges:  digitMatcherRanges,
+	}
+}
+
+type WordMatcher struct {
+	decoder
+	ranges []CharRange
+	chars  []rune
+}
+
+func (wm WordMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	c, width := wm.decode(b, index)
+	return MatchResult{match: matchRanges(wm.ranges, c) || matchChars(wm.chars, c), consume: width}
+}
+
+func (wm WordMatcher) isEpsilon() bool {
+	return false
+}
+
+var (
+	wordMarcherRanges  = []CharRange{{from: '0', to: '9'}, {from: 'a', to: 'z'}, {from: 'A', to: 'Z'}}
+	wordMatcherChars   = []rune{'_'}
+	digitMatcherRanges = []CharRange{{from: '0', to: '9'}}
+)
+
+func NewWordMatcher(d decoder) WordMatcher {
+	return WordMatcher{
+		decoder: d,
+		ranges:  wordMarcherRanges,
+		chars:   wordMatcherChars,
+	}
+}
+
+type CharRange struct {
+	from rune
+	to   rune
+}
+
+func (charRange CharRange) match(b rune) bool {
+	if b >= charRange.from && b <= charRange.to {
+		return true
+	}
+	return false
+}
+
+type CharacterGroupMatcher struct {
+	decoder
+	ranges     []CharRange
+	chars      []rune
+	isNegative bool
+	label      string
+}
+
+func (cgm CharacterGroupMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	c, width := cgm.decode(b, index)
+	base := matchChars(cgm.chars, c) || matchRanges(cgm.ranges, c)
+
+	if cgm.isNegative {
+		return MatchResult{match: !base, consume: width}
+	}
+
+	return MatchResult{match: base, consume: width}
+}
+
+func (lm CharacterGroupMatcher) isEpsilon() bool {
+	return false
+}
+
+func NewCharacterGroupMatcher(d decoder, ranges []CharRange, chars []rune, isNegative bool, label string) CharacterGroupMatcher {
+	return CharacterGroupMatcher{
+		decoder:    d,
+		ranges:     ranges,
+		chars:      chars,
+		isNegative: isNegative,
+		label:      label,
+	}
+}
+
+func matchRanges(ranges []CharRange, b rune) bool {
+	for _, item := range ranges {
+		if item.match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchChars(chars []rune, b rune) bool {
+	return slices.Contains(chars, b)
+}
+
+// StartOfStringMatcher (^) and EndOfStringMatcher ($) match at the start and
+// end of the input, in multi-line mode also right after / before a '\n'.
+type StartOfStringMatcher struct {
+	multiLine bool
+}
+
+func (startOfStringMatcher StartOfStringMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	if startOfStringMatcher.multiLine && index > 0 && b[index-1] == '\n' {
+		return MatchResult{match: true, consume: 0}
+	}
+	return MatchResult{match: index == 0, consume: 0}
+}
+
+func (lm StartOfStringMatcher) isEpsilon() bool {
+	return true
+}
+
+type EndOfStringMatcher struct {
+	multiLine bool
+}
+
+func (endOfStringMatcher EndOfStringMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	if endOfStringMatcher.multiLine && index < len(b) && b[index] == '\n' {
+		return MatchResult{match: true, consume: 0}
+	}
+	return MatchResult{match: len(b) == index, consume: 0}
+}
+
+func (lm EndOfStringMatcher) isEpsilon() bool {
+	return true
+}
+
+// StartOfTextMatcher (\A) and EndOfTextMatcher (\z) only match at the very
+// start and end of the input, whatever mode ^ and $ are in. \Z also matches
+// before a final newline.
+type StartOfTextMatcher struct{}
+
+func (startOfTextMatcher StartOfTextMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	return MatchResult{match: index == 0, consume: 0}
+}
+
+func (startOfTextMatcher StartOfTextMatcher) isEpsilon() bool {
+	return true
+}
+
+type EndOfTextMatcher struct {
+	beforeFinalNewline bool
+}
+
+func (endOfTextMatcher EndOfTextMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	if endOfTextMatcher.beforeFinalNewline && index == len(b)-1 && b[index] == '\n' {
+		return MatchResult{match: true, consume: 0}
+	}
+	return MatchResult{match: len(b) == index, consume: 0}
+}
+
+func (endOfTextMatcher EndOfTextMatcher) isEpsilon() bool {
+	return true
+}
+
+// WordBoundaryMatcher (\b) matches between a word character and a non word
+// character or the edge of the input, negated (\B) everywhere else.
+type WordBoundaryMatcher struct {
+	negated bool
+}
+
+func (wordBoundaryMatcher WordBoundaryMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	before := index > 0 && isWordChar(b[index-1])
+	after := index < len(b) && isWordChar(b[index])
+
+	return MatchResult{match: (before != after) != wordBoundaryMatcher.negated, consume: 0}
+}
+
+func (wordBoundaryMatcher WordBoundaryMatcher) isEpsilon() bool {
+	return true
+}
+
+func isWordChar(c byte) bool {
+	return matchRanges(wordMarcherRanges, rune(c)) || matchChars(wordMatcherChars, rune(c))
+}
+
+// AnyCharMatcher (.) matches any character but '\n', unless matchNewline is
+// set by the s flag.
+type AnyCharMatcher struct {
+	decoder
+	matchNewline bool
+}
+
+func (anyCharMatcher AnyCharMatcher) match(b []byte, index int, memory Memory) MatchResult {
+	c, width := anyCharMatcher.decode(b, index)
+	return MatchResult{match: anyCharMatcher.matchNewline || c != '\n', consume: width}
+}
+
+func (anyCharMatcher AnyCharMatcher) isEpsilon() bool {
+	return false
+}
+
+type BackreferenceMatcher struct {
+	decoder
+	groupId string
+	// capture slot of the group, resolved by compileProg
+	slot int
+	// compare the characters folded, see flags.go
+	fold bool
+}
+
+func (backreferenceMatcher BackreferenceMatcher) match(line []byte, index int, memory Memory) MatchResult {
+	memGroup, ok := memory.group(backreferenceMatcher.slot)
+
+	// a group that didn't take part in the match can't be repeated
+	if !ok {
+		return MatchResult{match: false, consume: 0}
+	}
+	if backreferenceMatcher.fold {
+		return backreferenceMatcher.matchFold(line[memGroup.start:memGroup.end], line, index)
+	}
+	i := index
+
+	for _, b := range line[memGroup.start:memGroup.end] {
+		if i >= len(line) || byte(b) != line[i] {
+			return MatchResult{match: false, consume: i - index}
+		}
+		i++
+	}
+
+	return MatchResult{match: true, consume: i - index}
+
+}
+
+// matchFold matches group at index character by character, ignoring case.
+func (backreferenceMatcher BackreferenceMatcher) matchFold(group []byte, line []byte, index int) MatchResult {
+	i := index
+	for j := 0; j < len(group); {
+		if i >= len(line) {
+			return MatchResult{match: false, consume: i - index}
+		}
+		want, wantWidth := backreferenceMatcher.decode(group, j)
+		got, gotWidth := backreferenceMatcher.decode(line, i)
+		if !equalFold(want, got) {
+			return MatchResult{match: false, consume: i - index}
+		}
+		j += wantWidth
+		i += gotWidth
+	}
+
+	return MatchResult{match: true, consume: i - index}
+}
+
+func (backreferenceMatcher BackreferenceMatcher) isEpsilon() bool {
+	return false
+}
+
+// AtomicStartMatcher and AtomicEndMatcher enclose an atomic group (?>...),
+// they always pass, the backtracking run gives them their meaning.
+type AtomicStartMatcher struct{}
+
+func (atomicStartMatcher AtomicStartMatcher) match(line []byte, index int, memory Memory) MatchResult {
+	return MatchResult{match: true, consume: 0}
+}
+
+func (atomicStartMatcher AtomicStartMatcher) isEpsilon() bool {
+	return true
+}
+
+type AtomicEndMatcher struct{}
+
+func (atomicEndMatcher AtomicEndMatcher) match(line []byte, index int, memory Memory) MatchResult {
+	return MatchResult{match: true, consume: 0}
+}
+
+func (atomicEndMatcher AtomicEndMatcher) isEpsilon() bool {
+	return true
+}
@@ -0,0 +1,42 @@
+package regex
+
+type NFATransition struct {
+	to      int
+	matcher Matcher
+}
+
+// NFA is the automaton built by the compiler, a state refers to another by
+// its position in States.
+type NFA struct {
+	States  []State
+	initial int
+	final   int
+}
+
+type State struct {
+	transitions []NFATransition
+	startGroup  []string
+	endGroup    []string
+}
+
+// newState adds a state without transitions and returns its position.
+func (n *NFA) newState() int {
+	n.States = append(n.States, State{})
+	return len(n.States) - 1
+}
+
+func (n *NFA) addTransition(from int, to int, matcher Matcher) {
+	n.States[from].transitions = append(n.States[from].transitions, NFATransition{to: to, matcher: matcher})
+}
+
+// addRepeatChoice adds the two ways out of from: repeat (going round once
+// more) and exit. A greedy quantifier prefers repeating, a lazy one exiting.
+func (n *NFA) addRepeatChoice(from int, repeat int, exit int, lazy bool) {
+	if lazy {
+		n.addTransition(from, exit, EpsilonMatcher{})
+		n.addTransition(from, repeat, EpsilonMatcher{})
+		return
+	}
+	n.addTransition(from, repeat, EpsilonMatcher{})
+	n.addTransition(from, exit, EpsilonMatcher{})
+}
@@ -0,0 +1,772 @@
+package regex
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ------------------ Parser ------------------
+// Builds an AST based on the following regex grammar:
+//
+// operator precedence:
+//
+//   Alternation     → Concatenation ("|" Concatenation)*
+//   Concatenation   → QuantifiedAtom+
+//   QuantifiedAtom  → Atom Quantifier?
+//   Atom            → Literal | Group | CharClass | Escape
+//   Quantifier      → ('?' | '*' | '+' | '{' Number (',' Number?)? '}') ('?' | '+')?
+//
+// ---------------------------------------------------------
+// Rule Explanations:
+//
+// 1. Atom
+//    The most basic building block of regex:
+//      - Literal      : a single character (e.g. "a", "9", ".")
+//      - Group        : a sub-expression in parentheses (e.g. "(ab|cd)"),
+//                       "(?:ab|cd)" groups without capturing,
+//                       "(?P<name>ab)" or "(?<name>ab)" names the group,
+//                       "(?>ab|a)" is atomic, "(?=ab)", "(?!ab)", "(?<=ab)"
+//                       and "(?<!ab)" look around without consuming,
+//                       "(?i)" and "(?i:ab)" set flags (see flags.go)
+//      - CharClass    : a character set in brackets (e.g. "[a-z0-9]", "[^abc]")
+//		- Escape	   : an espcped characters such as \d \w, or an assertion
+//		                 such as \b (word boundary) \A (start) \z (end)
+//
+// Focus on the next steps later; for now, only Atom is relevant
+// 2. Quantifier
+//    Specifies repetition of the preceding Atom:
+//      - '?'          : 0 or 1 occurrence
+//      - '*'          : 0 or more occurrences
+//      - '+'          : 1 or more occurrences
+//      - '{n}', '{n,m}': exact or ranged number of repetitions
+//    Quantifiers are greedy, followed by '?' (e.g. '*?') they're lazy and
+//    repeat as few times as possible, followed by '+' (e.g. '*+') they're
+//    possessive and never give back what they matched.
+//
+// 3. QuantifiedAtom
+//    Combines an Atom with an optional Quantifier.
+//    Example:
+//      - 'a*'      : Atom 'a' with '*' quantifier
+//      - '(dog)+'  : Atom is the group '(dog)' with '+' quantifier
+//      - '[0-9]?'  : Atom is character class '[0-9]' with '?' quantifier
+//
+// 4. Concatenation
+//    Matches a sequence of QuantifiedAtoms in order.
+//    Example:
+//      - 'abc'    : 'a' then 'b' then 'c'
+//      - 'a\d+'   : 'a' then '\d+' (digit repeated 1 or more)
+//
+// 5. Alternation
+//    Provides a choice between multiple Concatenations using '|'.
+//    Example:
+//      - 'cat|dog' : matches 'cat' OR 'dog'
+//      - 'a(b|c)d' : matches 'abd' OR 'acd'
+//
+// =========================================================
+
+const (
+	// the largest count a {n,m} quantifier accepts
+	maxRepeat = 1000
+	// the most states a {n,m} quantifier may copy its atom into
+	maxRepeatStates = 100000
+)
+
+type Parser struct {
+	pattern               string
+	pos                   int
+	capturingGroupCounter int
+	// groupNames[k] is the name of the k-th capturing group, "" when it's
+	// unnamed. It's filled before parsing so backreferences can look ahead.
+	groupNames []string
+	// inside \Q...\E, every character is a literal
+	quoting bool
+	// reads the pattern's characters, and is given to the matchers to read
+	// the input the same way
+	decoder decoder
+	// flags in effect at p.pos, see flags.go
+	flags flags
+}
+
+func (p Parser) isEnd() bool {
+	return p.pos >= len(p.pattern)
+}
+
+// peekNext returns the byte after p.pos, 0 at the end of the pattern.
+func (p Parser) peekNext() byte {
+	index := p.pos
+	index++
+	if index >= len(p.pattern) {
+		return 0
+	}
+	return p.pattern[index]
+}
+
+func (p Parser) isNextEnd() bool {
+	index := p.pos
+	index++
+	return index >= len(p.pattern)
+}
+
+func (p *Parser) parse() (Node, error) {
+	p.capturingGroupCounter = 1
+	groupNames, err := scanGroups(p.pattern)
+	if err != nil {
+		return nil, err
+	}
+	p.groupNames = groupNames
+
+	node, err := p.parseAlternation()
+	if err != nil {
+		return nil, err
+	}
+	// the alternation only stops early on a ')' closing no group
+	if !p.isEnd() {
+		return nil, p.errorAt(ErrUnexpectedParen, p.pos, p.pos+1)
+	}
+
+	return node, nil
+}
+
+// scanGroups lists the capturing groups of the pattern in the order their
+// opening brackets appear, which is also the order their ids are given in.
+func scanGroups(pattern string) ([]string, error) {
+	names := []string{""}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if strings.HasPrefix(pattern[i:], `\Q`) {
+				i = quoteEnd(pattern, i+2) - 1
+			} else {
+				i++
+			}
+		case '[':
+			// skip the class, a ']' right after '[' or '[^' closes it
+			for i++; i < len(pattern) && pattern[i] != ']'; i++ {
+				if strings.HasPrefix(pattern[i:], `\Q`) {
+					i = quoteEnd(pattern, i+2) - 1
+				} else if pattern[i] == '\\' {
+					i++
+				} else if end := strings.Index(pattern[i:], ":]"); strings.HasPrefix(pattern[i:], "[:") && end >= 0 {
+					i += end + 1
+				}
+			}
+		case '(':
+			name, next, named, err := groupName(pattern, i+1)
+			if err != nil {
+				return nil, err
+			}
+			if named {
+				if slices.Contains(names, name) {
+					return nil, syntaxError(pattern, ErrInvalidNamedCapture, i, next)
+				}
+				names = append(names, name)
+			} else if !strings.HasPrefix(pattern[i+1:], "?") {
+				names = append(names, "")
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// groupName reads the name of a group written (?P<name>...) or (?<name>...),
+// pos being the position right after '('. next is where the group's body
+// starts, named is false for any other kind of group.
+func groupName(pattern string, pos int) (name string, next int, named bool, err error) {
+	start := pos - 1
+	rest := pattern[pos:]
+	switch {
+	case strings.HasPrefix(rest, "?P<"):
+		pos += 3
+	case strings.HasPrefix(rest, "?<") && !strings.HasPrefix(rest, "?<=") && !strings.HasPrefix(rest, "?<!"):
+		pos += 2
+	default:
+		return "", pos, false, nil
+	}
+
+	end := strings.IndexByte(pattern[pos:], '>')
+	if end < 0 {
+		return "", pos, true, syntaxError(pattern, ErrInvalidNamedCapture, start, len(pattern))
+	}
+	name = pattern[pos : pos+end]
+	if !isGroupName(name) {
+		return "", pos, true, syntaxError(pattern, ErrInvalidNamedCapture, start, pos+end+1)
+	}
+
+	return name, pos + end + 1, true, nil
+}
+
+// isGroupName reports whether name is non-empty and made of word characters
+// only.
+func isGroupName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isWordChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Parser) parseAlternation() (Node, error) {
+	subs := []Node{}
+	for {
+		sub, err := p.parseConcatenation()
+		if err != nil {
+			return nil, err
+		}
+		// the alternatives of a non-capturing group, e.g. (?:a|b)|c, are
+		// alternatives of this one as well
+		if alternate, ok := sub.(Alternate); ok {
+			subs = append(subs, alternate.Subs...)
+		} else {
+			subs = append(subs, sub)
+		}
+		if p.isEnd() || p.pattern[p.pos] != '|' {
+			break
+		}
+		p.pos++
+	}
+	if len(subs) == 1 {
+		return subs[0], nil
+	}
+
+	return Alternate{Subs: subs}, nil
+}
+
+func (p *Parser) parseConcatenation() (Node, error) {
+	subs := []Node{}
+	for !p.isConcatenationEnd() {
+		sub, err := p.parseRepeat()
+		if err != nil {
+			return nil, err
+		}
+		switch sub := sub.(type) {
+		case nil:
+			// (?flags) only changes the flags
+		case Concat:
+			// a non-capturing group, e.g. (?:ab)c
+			subs = append(subs, sub.Subs...)
+		default:
+			subs = append(subs, sub)
+		}
+	}
+
+	// an empty alternative, e.g. in "a|" or "()", matches the empty string
+	switch len(subs) {
+	case 0:
+		return Empty{}, nil
+	case 1:
+		return subs[0], nil
+	}
+
+	return Concat{Subs: subs}, nil
+}
+
+// isConcatenationEnd reports whether p.pos is at the end of the pattern, of
+// an alternative or of a group.
+func (p Parser) isConcatenationEnd() bool {
+	return p.isEnd() || (!p.quoting && (p.pattern[p.pos] == ')' || p.pattern[p.pos] == '|'))
+}
+
+// parseRepeat parses an atom and its quantifier if it has one, nil when the
+// atom only sets flags.
+func (p *Parser) parseRepeat() (Node, error) {
+	atom, err := p.parseAtom()
+	if err != nil || atom == nil || p.isEnd() || p.quoting {
+		return atom, err
+	}
+
+	quantifierStart := p.pos
+	quantifierStop := quantifierEnd(p.pattern, p.pos)
+	if quantifierStop < 0 {
+		return atom, nil
+	}
+	// a '?' right after the quantifier makes it lazy: it repeats as few
+	// times as possible instead of as many. A '+' makes it possessive: it
+	// repeats as many times as possible and never gives any of them back.
+	modifier := p.quantifierModifier()
+	repeat := Repeat{Sub: atom, Lazy: modifier == '?', Possessive: modifier == '+'}
+	if !repeat.Possessive && p.flags.ungreedy {
+		repeat.Lazy = !repeat.Lazy
+	}
+
+	switch p.pattern[p.pos] {
+	case '*':
+		repeat.Min, repeat.Max = 0, -1
+	case '+':
+		repeat.Min, repeat.Max = 1, -1
+	case '?':
+		repeat.Min, repeat.Max = 0, 1
+	case '{':
+		lo, hi, _, _ := repeatBounds(p.pattern, p.pos)
+		if lo > maxRepeat || hi > maxRepeat || (hi >= 0 && hi < lo) {
+			return nil, p.errorAt(ErrInvalidRepeatSize, quantifierStart, quantifierStop)
+		}
+		repeat.Min, repeat.Max = lo, hi
+		// every repetition is a copy of the atom, the last one loops when
+		// there's no upper bound
+		if max(hi, lo, 1)*nodeStates(atom) > maxRepeatStates {
+			return nil, p.errorAt(ErrExpressionTooLarge, quantifierStart, quantifierStop)
+		}
+	}
+	p.pos = quantifierStop
+	if modifier != 0 {
+		p.pos++
+	}
+	// a quantifier can't be quantified again, e.g. a** or a{2}{3}
+	if next := quantifierEnd(p.pattern, p.pos); next >= 0 {
+		return nil, p.errorAt(ErrInvalidNestedRepeat, quantifierStart, next)
+	}
+
+	return repeat, nil
+}
+
+// quantifierModifier returns the '?' or '+' following the quantifier at
+// p.pos, 0 if there is none.
+func (p Parser) quantifierModifier() byte {
+	end := quantifierEnd(p.pattern, p.pos)
+	if end < 0 || end >= len(p.pattern) {
+		return 0
+	}
+	if p.pattern[end] == '?' || p.pattern[end] == '+' {
+		return p.pattern[end]
+	}
+
+	return 0
+}
+
+// quantifierEnd returns the position right after the quantifier at pos, its
+// modifier excluded, or -1 when there's no quantifier there.
+func quantifierEnd(pattern string, pos int) int {
+	if pos >= len(pattern) {
+		return -1
+	}
+	switch pattern[pos] {
+	case '+', '?', '*':
+		return pos + 1
+	case '{':
+		if _, _, end, ok := repeatBounds(pattern, pos); ok {
+			return end
+		}
+	}
+
+	return -1
+}
+
+// repeatBounds reads the {n}, {n,} or {n,m} at pos, hi being -1 when there's
+// no upper bound, and end the position after its '}'. ok is false for any
+// other '{', e.g. {,2} or {x}, which is then a literal.
+func repeatBounds(pattern string, pos int) (lo int, hi int, end int, ok bool) {
+	number := func() (int, bool) {
+		start := pos
+		for pos < len(pattern) && pattern[pos] >= '0' && pattern[pos] <= '9' {
+			pos++
+		}
+		if pos == start {
+			return 0, false
+		}
+		n, err := strconv.Atoi(pattern[start:pos])
+		if err != nil {
+			// too many digits, too large anyway
+			n = maxRepeat + 1
+		}
+		return n, true
+	}
+
+	if pos >= len(pattern) || pattern[pos] != '{' {
+		return 0, 0, 0, false
+	}
+	pos++
+	lo, ok = number()
+	if !ok {
+		return 0, 0, 0, false
+	}
+	hi = lo
+	if pos < len(pattern) && pattern[pos] == ',' {
+		pos++
+		if hi, ok = number(); !ok {
+			hi = -1
+		}
+	}
+	if pos >= len(pattern) || pattern[pos] != '}' {
+		return 0, 0, 0, false
+	}
+
+	return lo, hi, pos + 1, true
+}
+
+// parseAtom parses a single atom, nil for a group which only sets flags.
+func (p *Parser) parseAtom() (Node, error) {
+	if p.quoting {
+		return p.parseQuoted()
+	}
+	switch p.pattern[p.pos] {
+	case '*', '+', '?':
+		return nil, p.errorAt(ErrMissingRepeatArgument, p.pos, p.pos+1)
+	case '{':
+		if end := quantifierEnd(p.pattern, p.pos); end >= 0 {
+			return nil, p.errorAt(ErrMissingRepeatArgument, p.pos, end)
+		}
+		return p.parseLiteral()
+	case '\\':
+		return p.parseEscape()
+	case '[':
+		return p.parseCharClass()
+	case '^':
+		return p.parseCaretAnchor()
+	case '$':
+		return p.parseDollarAnchor()
+	case '.':
+		return p.parseDot()
+	case '(':
+		return p.parseGroup()
+	default:
+		return p.parseLiteral()
+	}
+}
+
+func (p *Parser) parseGroup() (Node, error) {
+	// (?:...) only groups, it's replaced by what it holds. (?>...) is an
+	// atomic group which doesn't capture either. Named groups are numbered
+	// like the others, the names were already collected by scanGroups.
+	for _, prefix := range []string{"?=", "?!", "?<=", "?<!"} {
+		if strings.HasPrefix(p.pattern[p.pos+1:], prefix) {
+			return p.parseLookaround(prefix)
+		}
+	}
+	// (?flags:...) doesn't capture either, (?flags) has no body and lasts
+	// until the enclosing group ends
+	groupStart := p.pos
+	groupFlags := p.flags
+	flagged := isFlagGroup(p.pattern, p.pos)
+	p.pos++
+	atomic := strings.HasPrefix(p.pattern[p.pos:], "?>")
+	capturing := !strings.HasPrefix(p.pattern[p.pos:], "?:") && !atomic && !flagged
+	group := Group{Atomic: atomic}
+	switch {
+	case flagged:
+		p.pos++
+		f, hasBody, err := p.parseFlags()
+		if err != nil {
+			return nil, err
+		}
+		if !hasBody {
+			p.flags = f
+			return nil, nil
+		}
+		groupFlags = f
+	case capturing:
+		name, next, _, err := groupName(p.pattern, p.pos)
+		if err != nil {
+			return nil, err
+		}
+		p.pos = next
+		group.Name = name
+		group.Index = p.capturingGroupCounter
+		p.capturingGroupCounter++
+	default:
+		p.pos += 2
+	}
+	sub, err := p.parseScoped(groupFlags)
+	if err != nil {
+		return nil, err
+	}
+	if p.isEnd() {
+		return nil, p.errorAt(ErrMissingParen, groupStart, len(p.pattern))
+	}
+	p.pos++
+
+	if !capturing && !atomic {
+		return sub, nil
+	}
+	group.Sub = sub
+
+	return group, nil
+}
+
+// parseLookaround parses (?=...), (?!...), (?<=...) or (?<!...), prefix is
+// what follows the opening bracket. See lookaround.go.
+func (p *Parser) parseLookaround(prefix string) (Node, error) {
+	start := p.pos
+	p.pos += 1 + len(prefix)
+	sub, err := p.parseScoped(p.flags)
+	if err != nil {
+		return nil, err
+	}
+	if p.isEnd() {
+		return nil, p.errorAt(ErrMissingParen, start, len(p.pattern))
+	}
+	p.pos++
+
+	return Lookaround{
+		Sub:      sub,
+		Behind:   strings.HasPrefix(prefix, "?<"),
+		Negative: strings.HasSuffix(prefix, "!"),
+	}, nil
+}
+
+// parseScoped parses the inside of a group with the flags f, flags set in
+// there are dropped when the group ends.
+func (p *Parser) parseScoped(f flags) (Node, error) {
+	saved := p.flags
+	p.flags = f
+	node, err := p.parseAlternation()
+	p.flags = saved
+
+	return node, err
+}
+
+func (p *Parser) parseDot() (Node, error) {
+	p.pos++
+	return AnyChar{MatchNewline: p.flags.dotNewline}, nil
+}
+
+func (p *Parser) parseEscape() (Node, error) {
+	start := p.pos
+	p.pos++
+	if p.isEnd() {
+		return nil, p.errorAt(ErrTrailingBackslash, start, p.pos)
+	}
+	esc := p.pattern[p.pos]
+	p.pos++
+	switch esc {
+	case 'b':
+		return Anchor{Kind: AnchorWordBoundary}, nil
+	case 'B':
+		return Anchor{Kind: AnchorNotWordBoundary}, nil
+	case 'A':
+		return Anchor{Kind: AnchorTextStart}, nil
+	case 'z':
+		return Anchor{Kind: AnchorTextEnd}, nil
+	case 'Z':
+		return Anchor{Kind: AnchorTextEndNewline}, nil
+	case 'Q':
+		if p.isEnd() || strings.HasPrefix(p.pattern[p.pos:], `\E`) {
+			p.pos = quoteEnd(p.pattern, p.pos)
+			return Empty{}, nil
+		}
+		p.quoting = true
+		return p.parseQuoted()
+	}
+	if ranges, ok := p.escapeClass(esc); ok {
+		return CharClass{Ranges: mergeRanges(ranges)}, nil
+	}
+	if esc == 'p' || esc == 'P' {
+		ranges, err := p.parseUnicodeClass(esc)
+		if err != nil {
+			return nil, err
+		}
+		return CharClass{Ranges: mergeRanges(ranges)}, nil
+	}
+
+	if esc >= '1' && esc <= '9' {
+		// \12 is group 12 only if the pattern has that many groups,
+		// otherwise it's \1 followed by a literal 2
+		id := int(esc - '0')
+		for !p.isEnd() && p.pattern[p.pos] >= '0' && p.pattern[p.pos] <= '9' {
+			next := id*10 + int(p.pattern[p.pos]-'0')
+			if next >= len(p.groupNames) {
+				break
+			}
+			id = next
+			p.pos++
+		}
+		if id >= len(p.groupNames) {
+			return nil, p.errorAt(ErrInvalidBackreference, start, p.pos)
+		}
+		return Backref{Index: id, Fold: p.flags.caseInsensitive}, nil
+	}
+	if esc == 'k' {
+		return p.parseNamedBackreference()
+	}
+
+	c, ok, err := p.parseEscapeChar(esc, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, p.escapeError(start)
+	}
+
+	return p.literal(c), nil
+}
+
+// parseQuoted parses one character of a \Q...\E quote, each one is an atom
+// of its own so a quantifier after \E applies to the last one only.
+func (p *Parser) parseQuoted() (Node, error) {
+	node, err := p.parseLiteral()
+	if p.isEnd() || strings.HasPrefix(p.pattern[p.pos:], `\E`) {
+		p.pos = quoteEnd(p.pattern, p.pos)
+		p.quoting = false
+	}
+
+	return node, err
+}
+
+// literal matches the character c, folded when case insensitive and c has
+// other cases.
+func (p *Parser) literal(c rune) Literal {
+	fold := p.flags.caseInsensitive && len(p.decoder.foldOrbit(c)) > 1
+	return Literal{Char: c, Fold: fold}
+}
+
+// parseNamedBackreference parses \k<name>, the leading \k is already consumed.
+func (p *Parser) parseNamedBackreference() (Node, error) {
+	start := p.pos - 2
+	if p.isEnd() || p.pattern[p.pos] != '<' {
+		return nil, p.errorAt(ErrInvalidBackreference, start, p.pos)
+	}
+	end := strings.IndexByte(p.pattern[p.pos:], '>')
+	if end < 0 {
+		return nil, p.errorAt(ErrInvalidBackreference, start, len(p.pattern))
+	}
+	name := p.pattern[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	id := slices.Index(p.groupNames, name)
+	if name == "" || id < 0 {
+		return nil, p.errorAt(ErrInvalidBackreference, start, p.pos)
+	}
+
+	return Backref{Index: id, Name: name, Fold: p.flags.caseInsensitive}, nil
+}
+
+func (p *Parser) parseCharClass() (Node, error) {
+	if !strings.Contains(p.pattern[p.pos:], "]") {
+		return nil, p.errorAt(ErrMissingBracket, p.pos, len(p.pattern))
+	}
+	start := p.pos
+	p.pos++
+	isNegative := false
+	if !p.isEnd() && p.pattern[p.pos] == '^' {
+		isNegative = true
+		p.pos++
+	}
+	ranges := []CharRange{}
+	chars := []rune{}
+	for !p.isEnd() && p.pattern[p.pos] != ']' {
+		if p.pattern[p.pos] == '[' {
+			set, ok, err := p.parsePosixClass()
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				ranges = append(ranges, set...)
+				continue
+			}
+		}
+		if strings.HasPrefix(p.pattern[p.pos:], `\Q`) {
+			end := quoteEnd(p.pattern, p.pos+2)
+			for quoted := strings.TrimSuffix(p.pattern[p.pos+2:end], `\E`); quoted != ""; {
+				c, width := p.decoder.decodeString(quoted)
+				chars = append(chars, c)
+				quoted = quoted[width:]
+			}
+			p.pos = end
+			continue
+		}
+		if p.pattern[p.pos] == '\\' && !p.isNextEnd() {
+			if set, ok := p.escapeClass(p.peekNext()); ok {
+				ranges = append(ranges, set...)
+				p.pos += 2
+				continue
+			}
+			if esc := p.peekNext(); esc == 'p' || esc == 'P' {
+				p.pos += 2
+				set, err := p.parseUnicodeClass(esc)
+				if err != nil {
+					return nil, err
+				}
+				ranges = append(ranges, set...)
+				continue
+			}
+		}
+
+		charStart := p.pos
+		char, err := p.parseClassChar()
+		if err != nil {
+			return nil, err
+		}
+		// a '-' right before ']' is a literal
+		if p.pos+1 < len(p.pattern) && p.pattern[p.pos] == '-' && p.pattern[p.pos+1] != ']' {
+			p.pos++
+			nextChar, err := p.parseClassChar()
+			if err != nil {
+				return nil, err
+			}
+			if nextChar < char {
+				return nil, p.errorAt(ErrInvalidCharRange, charStart, p.pos)
+			}
+			ranges = append(ranges, CharRange{from: char, to: nextChar})
+		} else {
+			chars = append(chars, char)
+		}
+	}
+	if p.isEnd() {
+		return nil, p.errorAt(ErrMissingBracket, start, len(p.pattern))
+	}
+	p.pos++
+	for _, c := range chars {
+		ranges = append(ranges, CharRange{from: c, to: c})
+	}
+	if p.flags.caseInsensitive {
+		ranges = p.foldRanges(ranges)
+	}
+
+	return CharClass{Ranges: mergeRanges(ranges), Negated: isNegative}, nil
+}
+
+// parseClassChar parses one character inside brackets, escaped or not.
+func (p *Parser) parseClassChar() (rune, error) {
+	start := p.pos
+	char, width := p.decoder.decodeString(p.pattern[p.pos:])
+	p.pos += width
+	if char != '\\' {
+		return char, nil
+	}
+	if p.isEnd() {
+		return 0, p.errorAt(ErrTrailingBackslash, start, p.pos)
+	}
+
+	esc := p.pattern[p.pos]
+	p.pos++
+	c, ok, err := p.parseEscapeChar(esc, true)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, p.escapeError(start)
+	}
+	if p.decoder.byteMode && c > 0xFF {
+		return 0, p.errorAt(ErrInvalidCharClass, start, p.pos)
+	}
+
+	return c, nil
+}
+
+func (p *Parser) parseLiteral() (Node, error) {
+	char, width := p.decoder.decodeString(p.pattern[p.pos:])
+	p.pos += width
+	return p.literal(char), nil
+}
+
+func (p *Parser) parseDollarAnchor() (Node, error) {
+	p.pos++
+	if p.flags.multiLine {
+		return Anchor{Kind: AnchorLineEnd}, nil
+	}
+	return Anchor{Kind: AnchorEnd}, nil
+}
+
+func (p *Parser) parseCaretAnchor() (Node, error) {
+	p.pos++
+	if p.flags.multiLine {
+		return Anchor{Kind: AnchorLineStart}, nil
+	}
+	return Anchor{Kind: AnchorStart}, nil
+}
@@ -0,0 +1,168 @@
+package regex
+
+import (
+	"bytes"
+	"slices"
+)
+
+// ------------------ Pike VM ------------------
+// Simulates the program breadth first (Thompson/Pike): all alive threads
+// advance over the input in lockstep and an instruction is entered at most
+// once per input position, so a search takes O(len(insts) * len(input)) time
+// no matter how ambiguous the pattern is.
+//
+// Threads are kept in priority order, the same order in which run would try
+// them (transitions[0] first, see addRepeatChoice). When a thread
+// reaches a final state every lower priority thread is dropped, which gives
+// the same leftmost-first result as the backtracking run.
+//
+// Each thread carries its own copy of the capture slots.
+//
+// Backreferences depend on what an earlier part of the same path matched and
+// atomic groups drop alternatives that were already tried, so neither can be
+// simulated in lockstep, patterns using them still go through run.
+// ---------------------------------------------
+
+// thread is a pending step: take transition of state, or report a match when
+// transition is -1.
+type thread struct {
+	state      int
+	transition int
+	caps       []int
+}
+
+// threadList is a sparse set of the states entered at one input position
+// plus the threads they produced, in priority order.
+type threadList struct {
+	sparse  []int
+	dense   []int
+	threads []thread
+}
+
+func newThreadList(size int) *threadList {
+	return &threadList{
+		sparse: make([]int, size),
+		dense:  make([]int, 0, size),
+	}
+}
+
+func (l *threadList) contains(state int) bool {
+	i := l.sparse[state]
+	return i < len(l.dense) && l.dense[i] == state
+}
+
+func (l *threadList) insert(state int) {
+	l.sparse[state] = len(l.dense)
+	l.dense = append(l.dense, state)
+}
+
+func (l *threadList) clear() {
+	l.dense = l.dense[:0]
+	l.threads = l.threads[:0]
+}
+
+// add follows every epsilon transition reachable from state at index pos and
+// queues the resulting threads on the list.
+func (p *prog) add(list *threadList, state int, pos int, caps []int, input []byte) {
+	if list.contains(state) {
+		return
+	}
+	list.insert(state)
+
+	s := p.insts[state]
+	if len(s.startSlots) > 0 || len(s.endSlots) > 0 {
+		caps = slices.Clone(caps)
+		for _, slot := range s.startSlots {
+			caps[slot] = pos
+		}
+		for _, slot := range s.endSlots {
+			caps[slot] = pos
+		}
+	}
+
+	if s.isFinal {
+		list.threads = append(list.threads, thread{state: state, transition: -1, caps: caps})
+		return
+	}
+
+	for i, transition := range s.transitions {
+		if !transition.matcher.isEpsilon() {
+			list.threads = append(list.threads, thread{state: state, transition: i, caps: caps})
+			continue
+		}
+		result := transition.matcher.match(input, pos, Memory{caps: caps})
+		if !result.match {
+			continue
+		}
+		next := caps
+		if result.caps != nil {
+			// a lookaround captured groups
+			next = result.caps
+		}
+		p.add(list, transition.to, pos, next, input)
+	}
+}
+
+// search returns the capture slots of the leftmost-first match that starts
+// at or after index, nil if there is none. With anchored set the match has
+// to start exactly at index.
+func (p *prog) search(input []byte, index int, anchored bool) []int {
+	return p.searchEndingAt(input, index, anchored, -1)
+}
+
+// searchEndingAt is search for a match ending exactly at end, or anywhere
+// when end is -1.
+func (p *prog) searchEndingAt(input []byte, index int, anchored bool, end int) []int {
+	clist := newThreadList(len(p.insts))
+	nlist := newThreadList(len(p.insts))
+	var matched []int
+
+	width := 0
+	for pos := index; ; pos += width {
+		if matched == nil && (!anchored || pos == index) {
+			// nothing in flight, skip to where the next match could start
+			if len(clist.threads) == 0 && len(p.prefix) > 0 && !anchored {
+				next := bytes.Index(input[pos:], p.prefix)
+				if next < 0 {
+					break
+				}
+				pos += next
+			}
+			caps := newCaps(p.numSlots)
+			caps[0] = pos
+			p.add(clist, p.start, pos, caps, input)
+		}
+		if len(clist.threads) == 0 && (matched != nil || anchored) {
+			break
+		}
+		// every thread reads the same character
+		width = p.charWidth(input, pos)
+
+		for _, t := range clist.threads {
+			if t.transition == -1 {
+				if end >= 0 && pos != end {
+					continue
+				}
+				matched = slices.Clone(t.caps)
+				matched[1] = pos
+				// lower priority threads can't win anymore
+				break
+			}
+			if pos >= len(input) {
+				continue
+			}
+			transition := p.insts[t.state].transitions[t.transition]
+			if transition.matcher.match(input, pos, Memory{}).match {
+				p.add(nlist, transition.to, pos+width, t.caps, input)
+			}
+		}
+
+		if pos >= len(input) || (end >= 0 && pos >= end) {
+			break
+		}
+		clist, nlist = nlist, clist
+		nlist.clear()
+	}
+
+	return matched
+}
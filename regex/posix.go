@@ -0,0 +1,69 @@
+package regex
+
+import (
+	"strings"
+)
+
+// ------------------ POSIX classes ------------------
+// Inside brackets [:name:] stands for one of the POSIX classes below and
+// [:^name:] for the characters outside it, e.g. [[:digit:]_] or
+// [^[:space:]]. The classes are ASCII only, like \d and \w.
+//
+// Equivalence classes [=a=] and collating symbols [.a.] depend on the locale,
+// they're rejected instead of silently matching something else. A '[' that
+// doesn't start any of those is a literal.
+// ---------------------------------------------
+
+var posixClasses = map[string][]CharRange{
+	"alnum":  {{from: '0', to: '9'}, {from: 'A', to: 'Z'}, {from: 'a', to: 'z'}},
+	"alpha":  {{from: 'A', to: 'Z'}, {from: 'a', to: 'z'}},
+	"blank":  {{from: '\t', to: '\t'}, {from: ' ', to: ' '}},
+	"cntrl":  {{from: 0x00, to: 0x1F}, {from: 0x7F, to: 0x7F}},
+	"digit":  {{from: '0', to: '9'}},
+	"graph":  {{from: '!', to: '~'}},
+	"lower":  {{from: 'a', to: 'z'}},
+	"print":  {{from: ' ', to: '~'}},
+	"punct":  {{from: '!', to: '/'}, {from: ':', to: '@'}, {from: '[', to: '`'}, {from: '{', to: '~'}},
+	"space":  {{from: '\t', to: '\r'}, {from: ' ', to: ' '}},
+	"upper":  {{from: 'A', to: 'Z'}},
+	"xdigit": {{from: '0', to: '9'}, {from: 'A', to: 'F'}, {from: 'a', to: 'f'}},
+}
+
+// parsePosixClass parses a [:name:] inside brackets, p.pos being on its
+// '['. ok is false when there's none and the '[' is a literal.
+func (p *Parser) parsePosixClass() (ranges []CharRange, ok bool, err error) {
+	rest := p.pattern[p.pos:]
+	if len(rest) < 2 {
+		return nil, false, nil
+	}
+	kind := rest[1]
+	if kind != ':' && kind != '=' && kind != '.' {
+		return nil, false, nil
+	}
+	end := strings.Index(rest[2:], string(kind)+"]")
+	if end < 0 {
+		return nil, false, nil
+	}
+	name := rest[2 : 2+end]
+	expression := rest[:end+4]
+
+	// equivalence classes and collating symbols
+	if kind != ':' {
+		return nil, true, p.errorAt(ErrUnsupportedCharClass, p.pos, p.pos+len(expression))
+	}
+
+	negated := strings.HasPrefix(name, "^")
+	ranges, ok = posixClasses[strings.TrimPrefix(name, "^")]
+	if !ok {
+		return nil, true, p.errorAt(ErrInvalidCharClass, p.pos, p.pos+len(expression))
+	}
+	p.pos += len(expression)
+	if p.flags.caseInsensitive {
+		ranges = p.foldRanges(ranges)
+	}
+	if negated {
+		return negateRanges(ranges), true, nil
+	}
+
+	return ranges, true, nil
+}
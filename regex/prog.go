@@ -0,0 +1,227 @@
+package regex
+
+import "strconv"
+
+// ------------------ Program ------------------
+// The NFA built by the compiler keeps what's handy while gluing fragments
+// together, e.g. groups by name. Before matching it's flattened into a dense
+// instruction array: instruction i is state i and every transition jumps to
+// an integer index.
+//
+// Capture groups are resolved to slots as well: slot 0 and 1 hold the bounds
+// of the whole match, slots 2k and 2k+1 the bounds of the k-th group.
+// ---------------------------------------------
+
+type instTransition struct {
+	to      int
+	matcher Matcher
+}
+
+type instruction struct {
+	transitions []instTransition
+	isFinal     bool
+	startGroup  []string
+	endGroup    []string
+	startSlots  []int
+	endSlots    []int
+}
+
+type prog struct {
+	insts    []instruction
+	start    int
+	numSlots int
+	// set when a matcher depends on what an earlier part of the same path
+	// matched (backreferences) or the pattern gives up alternatives (atomic
+	// groups), such programs can only be run by backtracking
+	needsBacktracking bool
+	// some matcher, maybe inside a lookaround, reads captured groups
+	hasBackreference bool
+	// readsCaps[pc] is set when a path from instruction pc goes through such
+	// a matcher, whether it leads to a match then depends on the path which
+	// led to pc and not only on pc and the position
+	readsCaps []bool
+	// literals every match starts with / contains, see literal.go
+	prefix   []byte
+	required []byte
+	// characters are single bytes instead of UTF-8 encoded runes
+	byteMode bool
+	// every match starts at the start of the input, see startAnchored
+	anchoredStart bool
+}
+
+// compileProg flattens the NFA, numGroups is the number of capturing groups
+// of the pattern (the highest group id).
+func compileProg(nfa *NFA, numGroups int, d decoder) *prog {
+	groupSlot := make(map[string]int, numGroups)
+	for id := 1; id <= numGroups; id++ {
+		groupSlot[strconv.Itoa(id)] = 2 * id
+	}
+
+	p := &prog{
+		insts:    make([]instruction, len(nfa.States)),
+		start:    nfa.initial,
+		numSlots: 2 * (numGroups + 1),
+		byteMode: d.byteMode,
+	}
+
+	for i, state := range nfa.States {
+		inst := &p.insts[i]
+		inst.isFinal = i == nfa.final
+		inst.startGroup = state.startGroup
+		inst.endGroup = state.endGroup
+		for _, transition := range state.transitions {
+			if bm, ok := transition.matcher.(BackreferenceMatcher); ok {
+				p.hasBackreference = true
+				p.needsBacktracking = true
+				bm.slot = -1
+				if slot, ok := groupSlot[bm.groupId]; ok {
+					bm.slot = slot
+				}
+				transition.matcher = bm
+			}
+			switch m := transition.matcher.(type) {
+			case AtomicStartMatcher, AtomicEndMatcher:
+				p.needsBacktracking = true
+			case LookaroundMatcher:
+				// only the backtracking run knows what was captured
+				if m.prog.hasBackreference {
+					p.hasBackreference = true
+					p.needsBacktracking = true
+				}
+			}
+			inst.transitions = append(inst.transitions, instTransition{
+				to:      transition.to,
+				matcher: transition.matcher,
+			})
+		}
+		for _, group := range state.startGroup {
+			inst.startSlots = append(inst.startSlots, groupSlot[group])
+		}
+		for _, group := range state.endGroup {
+			inst.endSlots = append(inst.endSlots, groupSlot[group]+1)
+		}
+	}
+	p.readsCaps = p.capsReaders()
+	p.prefix = p.literalPrefix()
+	p.required = p.requiredLiteral()
+	p.anchoredStart = p.startAnchored()
+
+	return p
+}
+
+// matchAt returns the capture slots of the leftmost-first match starting
+// exactly at index, nil if there is none.
+func (p *prog) matchAt(input []byte, index int) []int {
+	return p.matchEndingAt(input, index, -1)
+}
+
+// matchEndingAt is matchAt for a match ending exactly at end, or anywhere
+// when end is -1.
+func (p *prog) matchEndingAt(input []byte, index int, end int) []int {
+	if p.needsBacktracking {
+		return p.runWithCaps(input, index, newCaps(p.numSlots), end)
+	}
+
+	return p.searchEndingAt(input, index, true, end)
+}
+
+// capsReaders returns which instructions can reach a matcher reading the
+// captured groups, see readsCaps.
+func (p *prog) capsReaders() []bool {
+	readers := make([]bool, len(p.insts))
+	from := make([][]int, len(p.insts))
+	stack := []int{}
+	for pc, inst := range p.insts {
+		for _, transition := range inst.transitions {
+			from[transition.to] = append(from[transition.to], pc)
+			if readsCaps(transition.matcher) && !readers[pc] {
+				readers[pc] = true
+				stack = append(stack, pc)
+			}
+		}
+	}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, prev := range from[pc] {
+			if !readers[prev] {
+				readers[prev] = true
+				stack = append(stack, prev)
+			}
+		}
+	}
+
+	return readers
+}
+
+// readsCaps reports whether m looks at what was captured before it.
+func readsCaps(m Matcher) bool {
+	switch m := m.(type) {
+	case BackreferenceMatcher:
+		return true
+	case LookaroundMatcher:
+		return m.prog.hasBackreference
+	}
+
+	return false
+}
+
+// startAnchored reports whether every path from the start instruction goes
+// through ^ (not multi-line) or \A before consuming anything, so matches can
+// only start at the start of the input.
+func (p *prog) startAnchored() bool {
+	seen := make([]bool, len(p.insts))
+	stack := []int{p.start}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[pc] {
+			continue
+		}
+		seen[pc] = true
+		if p.insts[pc].isFinal {
+			return false
+		}
+
+		for _, transition := range p.insts[pc].transitions {
+			switch m := transition.matcher.(type) {
+			case StartOfTextMatcher:
+				continue
+			case StartOfStringMatcher:
+				if !m.multiLine {
+					continue
+				}
+			}
+			if !transition.matcher.isEpsilon() {
+				return false
+			}
+			stack = append(stack, transition.to)
+		}
+	}
+
+	return true
+}
+
+// charWidth returns the width of the character starting at index, the
+// searches move from one character to the next.
+func (p *prog) charWidth(input []byte, index int) int {
+	if index >= len(input) {
+		return 1
+	}
+	_, width := decoder{byteMode: p.byteMode}.decode(input, index)
+	return width
+}
+
+// newCaps returns capture slots with nothing captured yet.
+func newCaps(numSlots int) []int {
+	caps := make([]int, numSlots)
+	for i := range caps {
+		caps[i] = -1
+	}
+	return caps
+}
+
+// stateName is how instruction pc is labelled in the visualiser.
+func stateName(pc int) string {
+	return "q" + strconv.Itoa(pc)
+}
@@ -0,0 +1,443 @@
+// Package regex implements the regular expression engine behind mygrep.
+//
+// A pattern is parsed into a syntax tree (see Node), simplified (see
+// Simplify) and compiled into an NFA that is then simulated against the
+// input. The API loosely follows the standard library regexp package so
+// callers can switch between the two easily.
+package regex
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+// Regexp is the representation of a compiled regular expression.
+// A Regexp is safe for concurrent use by multiple goroutines.
+type Regexp struct {
+	pattern string
+	prog    *prog
+	// subexpNames[k] is the name of group k, "" when it has none
+	subexpNames []string
+	// lazy DFA caches, one per goroutine using the Regexp at a time, nil
+	// when the pattern can't be matched by a DFA
+	dfaPool *sync.Pool
+	// set when the pattern is built around an alternation of literals
+	ac *ahoCorasick
+}
+
+// Options tweaks how a pattern is compiled and matched, the zero value gives
+// the same result as Compile.
+type Options struct {
+	// DFACacheSize is the memory budget in bytes of the lazy DFA state
+	// cache. 0 means DefaultDFACacheSize, a negative value disables the
+	// DFA so everything is matched by the NFA.
+	DFACacheSize int
+	// Bytes matches the input byte by byte instead of as UTF-8 encoded
+	// runes, for binary input. The pattern's characters are bytes too,
+	// e.g. . matches any single byte.
+	Bytes bool
+	// CaseInsensitive makes letters match regardless of their case, like
+	// starting the pattern with (?i).
+	CaseInsensitive bool
+	// MultiLine makes ^ and $ also match right after and before every
+	// '\n', like starting the pattern with (?m). Input holding several
+	// lines, e.g. a whole file, can then be searched at once.
+	MultiLine bool
+	// WholeWord only lets the pattern match whole words, like grep -w: a
+	// match can't be preceded or followed by a word character. This is
+	// stricter than \b...\b when the pattern itself starts or ends with a
+	// non word character.
+	WholeWord bool
+}
+
+// Compile parses a regular expression and returns, if successful,
+// a Regexp that can be used to match against text.
+func Compile(pattern string) (*Regexp, error) {
+	return CompileWithOptions(pattern, Options{})
+}
+
+// CompileWithOptions is like Compile but lets the caller tune the engine.
+func CompileWithOptions(pattern string, opts Options) (*Regexp, error) {
+	re := &Regexp{pattern: pattern}
+	d := decoder{byteMode: opts.Bytes}
+	if !opts.Bytes && !utf8.ValidString(pattern) {
+		return nil, invalidUTF8Error(pattern)
+	}
+
+	node, groupNames, err := parse(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	if literals, ok := literalAlternatives(node, d); ok {
+		re.ac = newAhoCorasick(literals, d)
+	}
+	node = simplifier{decoder: d}.run(node)
+	nfa, err := compileNode(node, len(groupNames)-1, d)
+	if err != nil {
+		return nil, err
+	}
+	re.prog = compileProg(&nfa, len(groupNames)-1, d)
+	re.subexpNames = groupNames
+
+	cacheSize := opts.DFACacheSize
+	if cacheSize == 0 {
+		cacheSize = DefaultDFACacheSize
+	}
+	if cacheSize > 0 && re.prog.dfaEligible() {
+		re.dfaPool = &sync.Pool{New: func() any {
+			return newLazyDFA(re.prog, cacheSize)
+		}}
+	}
+
+	return re, nil
+}
+
+// Parse parses a regular expression into its syntax tree, see Node.
+func Parse(pattern string) (Node, error) {
+	return ParseWithOptions(pattern, Options{})
+}
+
+// ParseWithOptions is like Parse but applies the options which change the
+// meaning of the pattern, the others are ignored.
+func ParseWithOptions(pattern string, opts Options) (Node, error) {
+	if !opts.Bytes && !utf8.ValidString(pattern) {
+		return nil, invalidUTF8Error(pattern)
+	}
+	node, _, err := parse(pattern, opts)
+
+	return node, err
+}
+
+// parse returns the tree of pattern and the names of its groups.
+func parse(pattern string, opts Options) (Node, []string, error) {
+	parser := Parser{pattern: pattern, decoder: decoder{byteMode: opts.Bytes}}
+	parser.flags.caseInsensitive = opts.CaseInsensitive
+	parser.flags.multiLine = opts.MultiLine
+	word, _ := parser.escapeClass('w')
+	node, err := parser.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.WholeWord {
+		// (?<!\w)node(?!\w), wrapping the tree rather than the pattern
+		// keeps the pattern from closing the wrapper's groups
+		class := CharClass{Ranges: mergeRanges(word)}
+		node = Concat{Subs: []Node{
+			Lookaround{Sub: class, Behind: true, Negative: true},
+			node,
+			Lookaround{Sub: class, Negative: true},
+		}}
+	}
+
+	return node, parser.groupNames, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be parsed.
+func MustCompile(pattern string) *Regexp {
+	re, err := Compile(pattern)
+	if err != nil {
+		panic(`regex: Compile(` + pattern + `): ` + err.Error())
+	}
+
+	return re
+}
+
+// String returns the source text used to compile the regular expression.
+func (re *Regexp) String() string {
+	return re.pattern
+}
+
+// NumSubexp returns the number of parenthesized subexpressions.
+func (re *Regexp) NumSubexp() int {
+	return re.prog.numSlots/2 - 1
+}
+
+// SubexpNames returns the names of the parenthesized subexpressions, the name
+// of group k is at index k. Element 0, the whole match, and unnamed groups
+// have the name "". The slice shouldn't be modified.
+func (re *Regexp) SubexpNames() []string {
+	return re.subexpNames
+}
+
+// SubexpIndex returns the index of the group with the given name, -1 if there
+// is no such group.
+func (re *Regexp) SubexpIndex(name string) int {
+	if name == "" {
+		return -1
+	}
+
+	return slices.Index(re.subexpNames, name)
+}
+
+// Match reports whether b contains any match of the regular expression.
+func (re *Regexp) Match(b []byte) bool {
+	if !re.prog.canMatch(b) {
+		return false
+	}
+	if re.ac != nil {
+		return re.ac.search(re.prog, b, 0, false) != nil
+	}
+	if matched, ok := re.dfaMatch(b); ok {
+		return matched
+	}
+
+	return len(re.matchLine(b, 1)) > 0
+}
+
+// MatchString reports whether s contains any match of the regular expression.
+func (re *Regexp) MatchString(s string) bool {
+	return re.Match([]byte(s))
+}
+
+// Find returns the leftmost match in b, nil means no match.
+func (re *Regexp) Find(b []byte) []byte {
+	loc := re.FindIndex(b)
+	if loc == nil {
+		return nil
+	}
+
+	return b[loc[0]:loc[1]:loc[1]]
+}
+
+// FindString returns the leftmost match in s, "" means no match.
+func (re *Regexp) FindString(s string) string {
+	return string(re.Find([]byte(s)))
+}
+
+// FindIndex returns the start and end of the leftmost match in b, so the
+// match is b[loc[0]:loc[1]]. nil means no match.
+func (re *Regexp) FindIndex(b []byte) (loc []int) {
+	caps := re.FindSubmatchIndex(b)
+	if caps == nil {
+		return nil
+	}
+
+	return caps[0:2]
+}
+
+// FindSubmatch returns the leftmost match in b and the text of every group,
+// the whole match being element 0 and group k element k. Groups that didn't
+// take part in the match are nil.
+func (re *Regexp) FindSubmatch(b []byte) [][]byte {
+	return submatches(b, re.FindSubmatchIndex(b))
+}
+
+// FindSubmatchIndex returns index pairs of the leftmost match and its groups:
+// the group k is b[loc[2*k]:loc[2*k+1]]. A pair of -1 means the group didn't
+// take part in the match.
+func (re *Regexp) FindSubmatchIndex(b []byte) []int {
+	matches := re.matchLine(b, 1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return matches[0]
+}
+
+// FindAll returns successive non-overlapping matches in b, at most n of them
+// or all when n < 0. As in the standard library an empty match right after
+// the previous match is ignored.
+func (re *Regexp) FindAll(b []byte, n int) [][]byte {
+	return matchBytes(b, re.matchLine(b, n))
+}
+
+// FindAllString is the string version of FindAll.
+func (re *Regexp) FindAllString(s string, n int) []string {
+	matches := re.FindAll([]byte(s), n)
+	ss := make([]string, 0, len(matches))
+	for _, match := range matches {
+		ss = append(ss, string(match))
+	}
+
+	return ss
+}
+
+// FindAllIndex is the index version of FindAll.
+func (re *Regexp) FindAllIndex(b []byte, n int) [][]int {
+	locs := [][]int{}
+	for _, caps := range re.matchLine(b, n) {
+		locs = append(locs, caps[0:2])
+	}
+
+	return locs
+}
+
+// FindAllSubmatch is the FindSubmatch version of FindAll.
+func (re *Regexp) FindAllSubmatch(b []byte, n int) [][][]byte {
+	all := [][][]byte{}
+	for _, caps := range re.matchLine(b, n) {
+		all = append(all, submatches(b, caps))
+	}
+
+	return all
+}
+
+// FindAllSubmatchIndex is the FindSubmatchIndex version of FindAll.
+func (re *Regexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	return re.matchLine(b, n)
+}
+
+func submatches(b []byte, caps []int) [][]byte {
+	if caps == nil {
+		return nil
+	}
+	groups := make([][]byte, len(caps)/2)
+	for k := range groups {
+		if caps[2*k] >= 0 && caps[2*k+1] >= 0 {
+			groups[k] = b[caps[2*k]:caps[2*k+1]:caps[2*k+1]]
+		}
+	}
+
+	return groups
+}
+
+func matchBytes(b []byte, matches [][]int) [][]byte {
+	bs := make([][]byte, 0, len(matches))
+	for _, caps := range matches {
+		bs = append(bs, b[caps[0]:caps[1]:caps[1]])
+	}
+
+	return bs
+}
+
+// LineMatch is a single input line that matched together with every match
+// found on it.
+type LineMatch struct {
+	Line    []byte
+	Matches [][]byte
+	// Indexes holds the submatch index pairs of every match, laid out as in
+	// FindAllSubmatchIndex
+	Indexes [][]int
+}
+
+// MatchLines runs the expression against every line and returns the ones
+// that matched, the boolean reports whether there was any match at all.
+func (re *Regexp) MatchLines(lines [][]byte) ([]LineMatch, bool) {
+	match := false
+	multiLineMatches := []LineMatch{}
+	for _, line := range lines {
+		matches := re.matchLine(line, -1)
+
+		if len(matches) > 0 {
+			match = true
+			multiLineMatches = append(multiLineMatches, LineMatch{Line: line, Matches: matchBytes(line, matches), Indexes: matches})
+		}
+
+	}
+
+	return multiLineMatches, match
+}
+
+// MatchingLines returns the lines containing a match, the boolean reports
+// whether there was any. Unlike MatchLines it doesn't locate the matches, the
+// lazy DFA's answer is enough most of the time.
+func (re *Regexp) MatchingLines(lines [][]byte) ([][]byte, bool) {
+	matching := [][]byte{}
+	for _, line := range lines {
+		if re.Match(line) {
+			matching = append(matching, line)
+		}
+	}
+
+	return matching, len(matching) > 0
+}
+
+// dfaMatch asks the lazy DFA whether b contains a match, ok is false when
+// there's no DFA for the pattern or its cache thrashed.
+func (re *Regexp) dfaMatch(b []byte) (matched bool, ok bool) {
+	if re.dfaPool == nil {
+		return false, false
+	}
+	dfa := re.dfaPool.Get().(*lazyDFA)
+	defer re.dfaPool.Put(dfa)
+
+	return dfa.match(b)
+}
+
+// matchLine returns the capture slots of at most n matches in line, all of
+// them when n < 0.
+func (re *Regexp) matchLine(line []byte, n int) [][]int {
+	// most lines don't match at all, the required literal or the DFA reject
+	// them without starting the NFA
+	if !re.prog.canMatch(line) {
+		return [][]int{}
+	}
+	if re.ac == nil {
+		if matched, ok := re.dfaMatch(line); ok && !matched {
+			return [][]int{}
+		}
+	}
+
+	return re.findAllMatches(line, n)
+}
+
+// findAllMatches collects at most n matches (all when n < 0), searching again
+// where the previous match ended. After an empty match the search moves on by
+// one byte, otherwise it would find the same empty match forever.
+func (re *Regexp) findAllMatches(input []byte, n int) [][]int {
+	// matches of an anchored pattern can only start at 0
+	anchored := re.prog.anchoredStart
+	matches := [][]int{}
+	prevEnd := -1
+	for i := 0; i <= len(input) && (n < 0 || len(matches) < n); {
+		if i > 0 && anchored {
+			break
+		}
+		caps := re.find(input, i, anchored)
+
+		if caps == nil {
+			break
+		}
+		start, end := caps[0], caps[1]
+		accept := true
+		if end == i {
+			// empty match, ignore it if it touches the previous match
+			if start == prevEnd {
+				accept = false
+			}
+			i += re.prog.charWidth(input, i)
+		} else {
+			i = end
+		}
+		prevEnd = end
+
+		if accept {
+			matches = append(matches, caps)
+		}
+	}
+
+	return matches
+}
+
+// find returns the capture slots of the leftmost-first match that starts at
+// or after index, nil if there is none.
+func (re *Regexp) find(input []byte, index int, anchored bool) []int {
+	if re.ac != nil {
+		return re.ac.search(re.prog, input, index, anchored)
+	}
+	if !re.prog.needsBacktracking {
+		return re.prog.search(input, index, anchored)
+	}
+
+	// what failed from one start fails from the next ones too
+	visited := re.prog.newVisited(index)
+	for i := index; i <= len(input); i += re.prog.charWidth(input, i) {
+		if len(re.prog.prefix) > 0 && !anchored {
+			next := bytes.Index(input[i:], re.prog.prefix)
+			if next < 0 {
+				break
+			}
+			i += next
+		}
+		if caps := re.prog.backtrack(input, i, newCaps(re.prog.numSlots), visited, -1); caps != nil {
+			return caps
+		}
+		if anchored {
+			break
+		}
+	}
+
+	return nil
+}
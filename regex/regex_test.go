@@ -0,0 +1,1995 @@
+package regex
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Data struct {
+	pattern string
+	input   string
+	matches []string
+}
+
+func stringSliceEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLiteralMatching(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "a",
+			input:   "apple",
+			matches: []string{"a"},
+		},
+		{
+			pattern: "a",
+			input:   "dog",
+			matches: []string{},
+		},
+		{
+			pattern: "d",
+			input:   "dog",
+			matches: []string{"d"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestDigitMatching(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "\\d",
+			input:   "1",
+			matches: []string{"1"},
+		},
+		{
+			pattern: "\\d",
+			input:   "123",
+			matches: []string{"1", "2", "3"},
+		},
+		{
+			pattern: "\\d",
+			input:   "a3",
+			matches: []string{"3"},
+		},
+		{
+			pattern: "\\d",
+			input:   "a",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+
+}
+
+func TestWordMatching(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "\\w",
+			input:   "12a",
+			matches: []string{"1", "2", "a"},
+		},
+		{
+			pattern: "\\w\\w",
+			input:   "12ab",
+			matches: []string{"12", "ab"},
+		},
+		{
+			pattern: "\\w",
+			input:   "1$2",
+			matches: []string{"1", "2"},
+		},
+		{
+			pattern: "\\w",
+			input:   "%$#",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestCharGroupMatching(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "[\\w]",
+			input:   "12a",
+			matches: []string{"1", "2", "a"},
+		},
+		{
+			pattern: "[\\w\\w]",
+			input:   "12ab",
+			matches: []string{"1", "2", "a", "b"},
+		},
+		{
+			pattern: "[\\w]",
+			input:   "1$2",
+			matches: []string{"1", "2"},
+		},
+		{
+			pattern: "[\\2]",
+			input:   "%$#",
+			matches: []string{},
+		},
+		{
+			pattern: "[^abcd]",
+			input:   "abcde",
+			matches: []string{"e"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestCombingCharClass(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "\\d\\d\\d apples",
+			input:   "sally has 124 apples",
+			matches: []string{"124 apples"},
+		},
+		{
+			pattern: "\\d \\w\\w\\ws",
+			input:   "sally has 3 dogs",
+			matches: []string{"3 dogs"},
+		},
+		{
+			pattern: "\\d\\\\d\\\\d apples",
+			input:   "sally has 12 apples",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "^12",
+			input:   "123",
+			matches: []string{"12"},
+		},
+		{
+			pattern: "^123",
+			input:   "234",
+			matches: []string{},
+		},
+		{
+			pattern: "^12$",
+			input:   "123",
+			matches: []string{},
+		},
+		{
+			pattern: "^12$",
+			input:   "12",
+			matches: []string{"12"},
+		},
+		{
+			pattern: "^log",
+			input:   "slog",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestPlus(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "a+",
+			input:   "aaaa",
+			matches: []string{"aaaa"},
+		},
+		{
+			pattern: "ca+t",
+			input:   "caat",
+			matches: []string{"caat"},
+		},
+		{
+			pattern: "ca+t",
+			input:   "caart",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestQuestionmark(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "a?",
+			input:   "aaaa",
+			matches: []string{"a", "a", "a", "a"},
+		},
+		{
+			pattern: "a?b",
+			input:   "b",
+			matches: []string{"b"},
+		},
+		{
+			pattern: "a?c",
+			input:   "b",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestAsterik(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "ca*t",
+			input:   "ct",
+			matches: []string{"ct"},
+		},
+		{
+			pattern: "ca*t",
+			input:   "caaat",
+			matches: []string{"caaat"},
+		},
+		{
+			pattern: "ca*t",
+			input:   "dog",
+			matches: []string{},
+		},
+		{
+			pattern: "k\\d*t",
+			input:   "kt",
+			matches: []string{"kt"},
+		},
+		{
+			pattern: "k\\d*t",
+			input:   "k1t",
+			matches: []string{"k1t"},
+		},
+		{
+			pattern: "k[abc]*t",
+			input:   "kt",
+			matches: []string{"kt"},
+		},
+		{
+			pattern: "k[abc]*t",
+			input:   "kat",
+			matches: []string{"kat"},
+		},
+		{
+			pattern: "k[abc]*t",
+			input:   "kabct",
+			matches: []string{"kabct"},
+		},
+		{
+			pattern: "k[abc]*t",
+			input:   "kxt",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestDot(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "a.b",
+			input:   "aab",
+			matches: []string{"aab"},
+		},
+		{
+			pattern: "a.",
+			input:   "aa",
+			matches: []string{"aa"},
+		},
+		{
+			pattern: "a.",
+			input:   "b",
+			matches: []string{},
+		},
+		{
+			pattern: "a.+",
+			input:   "accc",
+			matches: []string{"accc"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "(a+)",
+			input:   "aaa",
+			matches: []string{"aaa"},
+		},
+		{
+			pattern: "(a)",
+			input:   "a",
+			matches: []string{"a"},
+		},
+		{
+			pattern: "(b)",
+			input:   "a",
+			matches: []string{},
+		},
+		{
+			pattern: "^I see (\\d (cat|dog|cow)s?(, | and )?)+$",
+			input:   "I see 1 cat, 2 dogs and 3 cows",
+			matches: []string{"I see 1 cat, 2 dogs and 3 cows"},
+		},
+		{
+			pattern: "^I see (\\d (cat|dog|cow)(, | and )?)+$",
+			input:   "I see 1 cat, 2 dogs and 3 cows",
+			matches: []string{},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestAlternation(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "(a|b)",
+			input:   "ab",
+			matches: []string{"a", "b"},
+		},
+		{
+			pattern: "(abc|def)",
+			input:   "abc",
+			matches: []string{"abc"},
+		},
+		{
+			pattern: "(abc|r)",
+			input:   "aa",
+			matches: []string{},
+		},
+		{
+			pattern: "a|b",
+			input:   "a",
+			matches: []string{"a"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestBackreference(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "(cat) and \\1",
+			input:   "cat and cat",
+			matches: []string{"cat and cat"},
+		},
+		{
+			pattern: "((c.t|d.g) and (f..h|b..d)), \\2 with \\3, \\1",
+			input:   "bat and fish, bat with fish, bat and fish",
+			matches: []string{},
+		},
+
+		{
+			pattern: "^((\\w+) (\\w+)) is made of \\2 and \\3. love \\1$",
+			input:   "apple pie is made of apple and pie. love apple pie",
+			matches: []string{"apple pie is made of apple and pie. love apple pie"},
+		},
+		{
+			pattern: "((\\w\\w\\w\\w) (\\d\\d\\d)) is doing \\2 \\3 times, and again \\1 times",
+			input:   "grep 101 is doing grep 101 times, and again grep 101 times",
+			matches: []string{"grep 101 is doing grep 101 times, and again grep 101 times"},
+		},
+		{
+			pattern: "((how+dy) (he?y) there)\" is made up of \"\\2\" and \"\\3\". \\1",
+			input:   "howwdy hey there\" is made up of \"howwdy\" and \"hey\". howwdy hey there",
+			matches: []string{"howwdy hey there\" is made up of \"howwdy\" and \"hey\". howwdy hey there"},
+		},
+		{
+			// an empty group matches at the end of the input too
+			pattern: "(a*)b\\1",
+			input:   "b",
+			matches: []string{"b"},
+		},
+		{
+			pattern: "(a*)b\\1",
+			input:   "bc",
+			matches: []string{"b"},
+		},
+		{
+			pattern: "(x?)abc\\1$",
+			input:   "abc",
+			matches: []string{"abc"},
+		},
+		{
+			pattern: "(?<n>x?)y\\k<n>",
+			input:   "y",
+			matches: []string{"y"},
+		},
+		{
+			pattern: "(?i)(a*)b\\1",
+			input:   "B",
+			matches: []string{"B"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestQuantiier(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "ca{3}t",
+			input:   "caaat",
+			matches: []string{"caaat"},
+		},
+		{
+			pattern: "ca{2}t",
+			input:   "caaat",
+			matches: []string{},
+		},
+		{
+			pattern: "ca{3}t",
+			input:   "caaaaat",
+			matches: []string{},
+		},
+		{
+			pattern: "ca{2,}t",
+			input:   "caaaaat",
+			matches: []string{"caaaaat"},
+		},
+		{
+			pattern: "ca{2,}t",
+			input:   "cat",
+			matches: []string{},
+		},
+		{
+			pattern: "ca{2,4}t",
+			input:   "caat",
+			matches: []string{"caat"},
+		},
+		{
+			pattern: "ca{2,4}t",
+			input:   "caaat",
+			matches: []string{"caaat"},
+		},
+		{
+			pattern: "ca{2,4}t",
+			input:   "caaaat",
+			matches: []string{"caaaat"},
+		},
+		{
+			pattern: "ca{2,4}t",
+			input:   "caaaaat",
+			matches: []string{},
+		},
+		{
+			pattern: "d\\d{2}g",
+			input:   "d42g",
+			matches: []string{"d42g"},
+		},
+		{
+			pattern: "d\\d{2}g",
+			input:   "d1g",
+			matches: []string{},
+		},
+		{
+			pattern: "d\\d{2}g",
+			input:   "d123g",
+			matches: []string{},
+		},
+		{
+			pattern: "n\\d{1,3}m",
+			input:   "n123m",
+			matches: []string{"n123m"},
+		},
+		{
+			pattern: "n\\d{1,3}m",
+			input:   "n1234m",
+			matches: []string{},
+		},
+		{
+			pattern: "x\\d{3,}y",
+			input:   "x9999y",
+			matches: []string{"x9999y"},
+		},
+		{
+			pattern: "x\\d{3,}y",
+			input:   "x42y",
+			matches: []string{},
+		},
+		{
+			pattern: "c[xyz]{4}w",
+			input:   "czyxzw",
+			matches: []string{"czyxzw"},
+		},
+		{
+			pattern: "c[xyz]{4}w",
+			input:   "cxyzw",
+			matches: []string{},
+		},
+
+		{
+			pattern: "b[aeiou]{2,}r",
+			input:   "baeiour",
+			matches: []string{"baeiour"},
+		},
+		{
+			pattern: "b[aeiou]{2,}r",
+			input:   "bar",
+			matches: []string{},
+		},
+		{
+			pattern: "p[xyz]{2,3}q",
+			input:   "pzzzq",
+			matches: []string{"pzzzq"},
+		},
+		{
+			pattern: "p[xyz]{2,3}q",
+			input:   "pxq",
+			matches: []string{},
+		},
+		{
+			pattern: "p[xyz]{2,3}q",
+			input:   "pxyzyq",
+			matches: []string{},
+		},
+		{
+			pattern: "^\\d{4}-\\d{1,2}-\\d{1,2} \\d{1,2}:\\d{1,2} LOG (INFO|DEBUG) \\w+$",
+			input:   "2022-9-6 18:19 LOG INFO session_validated",
+			matches: []string{"2022-9-6 18:19 LOG INFO session_validated"},
+		},
+		{
+			pattern: "^\\d{4}-\\d{1,2}",
+			input:   "2022-9",
+			matches: []string{"2022-9"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestPublicAPI(t *testing.T) {
+	re := MustCompile("\\d+ apples")
+
+	if re.String() != "\\d+ apples" {
+		t.Errorf("Expected String() to return the source pattern, got: %v", re.String())
+	}
+	if !re.MatchString("sally has 12 apples") {
+		t.Errorf("Expected pattern to match")
+	}
+	if re.Match([]byte("sally has apples")) {
+		t.Errorf("Expected pattern not to match")
+	}
+	if got := re.FindString("12 apples, 3 apples"); got != "12 apples" {
+		t.Errorf("Expected leftmost match 12 apples, got: %v", got)
+	}
+	if got := re.FindAllString("12 apples, 3 apples", -1); !stringSliceEqual(got, []string{"12 apples", "3 apples"}) {
+		t.Errorf("Expected to find these matches: %v, got: %v", []string{"12 apples", "3 apples"}, got)
+	}
+
+	lines, ok := re.MatchLines([][]byte{[]byte("1 apples"), []byte("pears"), []byte("2 apples")})
+	if !ok || len(lines) != 2 {
+		t.Errorf("Expected 2 matching lines, got: %v", len(lines))
+	}
+}
+
+func TestMatchingLines(t *testing.T) {
+	lines := [][]byte{[]byte("cat and cat"), []byte("dog"), []byte(""), []byte("cats and dogs"), []byte("x1y")}
+	patterns := []string{"cat", "\\bdogs?$", "^$", "(cat) and \\1", "\\d", "cat|dog", "zebra"}
+
+	for _, pattern := range patterns {
+		t.Run(fmt.Sprintf("Checking pattern %v", pattern), func(t *testing.T) {
+			re := MustCompile(pattern)
+			expected, expectedOk := re.MatchLines(lines)
+			got, ok := re.MatchingLines(lines)
+
+			if ok != expectedOk || len(got) != len(expected) {
+				t.Fatalf("Expected %v matching lines, got: %v", len(expected), len(got))
+			}
+			for i, line := range got {
+				if string(line) != string(expected[i].Line) {
+					t.Errorf("Expected line %q, got: %q", expected[i].Line, line)
+				}
+			}
+		})
+	}
+}
+
+func TestMustCompilePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected MustCompile to panic on invalid pattern")
+		}
+	}()
+	MustCompile("[abc")
+}
+
+func TestConcurrentCompileAndMatch(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "^((\\w+) (\\w+)) is made of \\2 and \\3. love \\1$",
+			input:   "apple pie is made of apple and pie. love apple pie",
+			matches: []string{"apple pie is made of apple and pie. love apple pie"},
+		},
+		{
+			pattern: "ca{2,4}t",
+			input:   "caaat",
+			matches: []string{"caaat"},
+		},
+		{
+			pattern: "(abc|def)",
+			input:   "abc",
+			matches: []string{"abc"},
+		},
+	}
+	shared := MustCompile("(\\d+) (cat|dog)s?")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, item := range data {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				re, err := Compile(item.pattern)
+				if err != nil {
+					t.Errorf("Unexpected error compiling %v: %v", item.pattern, err)
+					return
+				}
+				matchesString := re.FindAllString(item.input, -1)
+				if !stringSliceEqual(matchesString, item.matches) {
+					t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+				}
+
+				if got := shared.FindString("I have 3 dogs"); got != "3 dogs" {
+					t.Errorf("Expected shared regex to find 3 dogs, got: %v", got)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestPikeVMMatchesBacktracking(t *testing.T) {
+	data := []Data{
+		{pattern: "a+", input: "baaab"},
+		{pattern: "a?", input: "aaaa"},
+		{pattern: "(abc|ab)c", input: "xabcc"},
+		{pattern: "(a|ab)(c|bcd)", input: "abcd"},
+		{pattern: "ca{2,4}t", input: "caaaat"},
+		{pattern: "x\\d{3,}y", input: "x9999y"},
+		{pattern: "^I see (\\d (cat|dog|cow)s?(, | and )?)+$", input: "I see 1 cat, 2 dogs and 3 cows"},
+		{pattern: "[^abcd]+", input: "abcdef"},
+		{pattern: "<(.+?)>(.*?)</", input: "<b>x</b>"},
+		{pattern: "a{1,3}?(a*)", input: "aaaa"},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			for i := 0; i < len(item.input); i++ {
+				expected := re.prog.run([]byte(item.input), i)
+				caps := re.prog.search([]byte(item.input), i, true)
+
+				if !slices.Equal(expected, caps) {
+					t.Errorf("At %v expected backtracking result %v, got: %v", i, expected, caps)
+				}
+			}
+		})
+	}
+}
+
+func TestPikeVMNoCatastrophicBacktracking(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "(a*)*b",
+			input:   strings.Repeat("a", 5000),
+			matches: []string{},
+		},
+		{
+			pattern: "(a|aa)+$",
+			input:   strings.Repeat("a", 5000) + "b",
+			matches: []string{},
+		},
+		{
+			pattern: "(x+x+)+y",
+			input:   strings.Repeat("x", 5000) + "y",
+			matches: []string{strings.Repeat("x", 5000) + "y"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			matchesString := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find %v matches, got: %v", len(item.matches), len(matchesString))
+			}
+		})
+	}
+}
+
+func TestCompileProg(t *testing.T) {
+	patterns := []string{"a", "(a|b)+c", "ca{2,4}t", "^((\\w+) (\\w+)) is \\2$"}
+
+	for _, pattern := range patterns {
+		t.Run(fmt.Sprintf("Checking pattern %v", pattern), func(t *testing.T) {
+			re := MustCompile(pattern)
+			graph := re.Graph()
+
+			if len(graph.States) != len(re.prog.insts) {
+				t.Errorf("Expected %v graph states, got: %v", len(re.prog.insts), len(graph.States))
+			}
+			if graph.InitState != stateName(re.prog.start) {
+				t.Errorf("Expected init state %v, got: %v", stateName(re.prog.start), graph.InitState)
+			}
+			for pc, inst := range re.prog.insts {
+				for _, transition := range inst.transitions {
+					if transition.to < 0 || transition.to >= len(re.prog.insts) {
+						t.Errorf("Instruction %v jumps out of the program: %v", pc, transition.to)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLiteralExtraction(t *testing.T) {
+	data := []struct {
+		pattern  string
+		prefix   string
+		required string
+	}{
+		{pattern: "error: \\d+", prefix: "error: ", required: "error: "},
+		{pattern: "\\d+ apples", prefix: "", required: " apples"},
+		{pattern: "(abc|abd)", prefix: "ab", required: "ab"},
+		{pattern: "a?bc", prefix: "", required: "bc"},
+		{pattern: "(cat|dog)s", prefix: "", required: "s"},
+		{pattern: "^log (INFO|DEBUG) \\w+ done$", prefix: "log ", required: " done"},
+		{pattern: "ca{2}t", prefix: "caat", required: "caat"},
+		{pattern: "ab|cd", prefix: "", required: ""},
+		{pattern: "x*", prefix: "", required: ""},
+		{pattern: "(cat) and \\1", prefix: "cat and ", required: "cat and "},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+
+			if string(re.prog.prefix) != item.prefix {
+				t.Errorf("Expected prefix %q, got: %q", item.prefix, re.prog.prefix)
+			}
+			if string(re.prog.required) != item.required {
+				t.Errorf("Expected required literal %q, got: %q", item.required, re.prog.required)
+			}
+		})
+	}
+}
+
+func TestLiteralAcceleration(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "error: \\d+",
+			input:   "info: 1 error: x error: 42 error: 7",
+			matches: []string{"error: 42", "error: 7"},
+		},
+		{
+			pattern: "\\d+ apples",
+			input:   "12 pears, 3 apples",
+			matches: []string{"3 apples"},
+		},
+		{
+			pattern: "\\d+ apples",
+			input:   "12 pears, 3 bananas",
+			matches: []string{},
+		},
+		{
+			pattern: "(cat) and \\1",
+			input:   "cat and dog, cat and cat",
+			matches: []string{"cat and cat"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, _ := Compile(item.pattern)
+			matches := re.FindAll([]byte(item.input), -1)
+			matchesString := []string{}
+			for _, item := range matches {
+				matchesString = append(matchesString, string(item))
+			}
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matchesString)
+			}
+		})
+	}
+}
+
+func TestFindAllLongLines(t *testing.T) {
+	data := []Data{
+		{
+			pattern: "\\d",
+			input:   strings.Repeat("1a", 100),
+			matches: strings.Split(strings.Repeat("1", 100), ""),
+		},
+		{
+			pattern: "ab",
+			input:   strings.Repeat("ab", 50) + "x",
+			matches: strings.Split(strings.TrimSuffix(strings.Repeat("ab,", 50), ","), ","),
+		},
+		{
+			pattern: "^ab",
+			input:   strings.Repeat("ab", 50),
+			matches: []string{"ab"},
+		},
+		{
+			pattern: "x",
+			input:   strings.Repeat("a", 100) + "x",
+			matches: []string{"x"},
+		},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			matchesString := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find %v matches, got: %v", len(item.matches), len(matchesString))
+			}
+		})
+	}
+}
+
+func TestFindAllEmptyMatches(t *testing.T) {
+	data := []Data{
+		{pattern: "a*", input: "baaac", matches: []string{"", "aaa", ""}},
+		{pattern: "a?", input: "b", matches: []string{"", ""}},
+		{pattern: "a?", input: "aaaa", matches: []string{"a", "a", "a", "a"}},
+		{pattern: "x*", input: "", matches: []string{""}},
+		{pattern: "^", input: "ab", matches: []string{""}},
+		{pattern: "$", input: "ab", matches: []string{""}},
+		{pattern: "^$", input: "", matches: []string{""}},
+		{pattern: "(a|b)*", input: "abcab", matches: []string{"ab", "ab"}},
+		{pattern: "(cat) and \\1|x?", input: "cat and cat!", matches: []string{"cat and cat", ""}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			matchesString := MustCompile(item.pattern).FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matchesString, item.matches) {
+				t.Errorf("Expected to find these matches: %q, got: %q", item.matches, matchesString)
+			}
+
+			// same results as the standard library, which has no backreferences
+			if !strings.Contains(item.pattern, "\\1") {
+				expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+				if !stringSliceEqual(matchesString, expected) {
+					t.Errorf("Expected the same matches as regexp: %q, got: %q", expected, matchesString)
+				}
+			}
+		})
+	}
+}
+
+func TestFindAllLimit(t *testing.T) {
+	re := MustCompile("\\d")
+
+	for _, n := range []int{0, 1, 3, 10} {
+		matches := re.FindAllString("1 2 3 4 5", n)
+		if len(matches) != min(n, 5) {
+			t.Errorf("Expected %v matches for n=%v, got: %v", min(n, 5), n, matches)
+		}
+	}
+	if matches := re.FindAllString("1 2 3 4 5", -1); len(matches) != 5 {
+		t.Errorf("Expected every match for n=-1, got: %v", matches)
+	}
+}
+
+func TestFindSubmatchIndex(t *testing.T) {
+	data := []struct {
+		pattern string
+		input   string
+		indexes []int
+	}{
+		{pattern: "a(b)c", input: "xabc", indexes: []int{1, 4, 2, 3}},
+		{pattern: "(a)|(b)", input: "b", indexes: []int{0, 1, -1, -1, 0, 1}},
+		{pattern: "(\\w)+", input: "ab c", indexes: []int{0, 2, 1, 2}},
+		{pattern: "(a(b)?)c", input: "ac", indexes: []int{0, 2, 0, 1, -1, -1}},
+		{pattern: "(\\w+) \\1", input: "say hey hey", indexes: []int{4, 11, 4, 7}},
+		{pattern: "cat|dog", input: "hotdog", indexes: []int{3, 6}},
+		{pattern: "x(y)", input: "abc", indexes: nil},
+		// groups inside a positive lookaround capture
+		{pattern: "(a)(?=(b))", input: "ab", indexes: []int{0, 1, 0, 1, 1, 2}},
+		{pattern: "(?=(a+))a*b\\1", input: "aaba", indexes: []int{1, 4, 1, 2}},
+		{pattern: "(?<=(a))b", input: "ab", indexes: []int{1, 2, 0, 1}},
+		{pattern: "(a)(?!(b))", input: "ac", indexes: []int{0, 1, 0, 1, -1, -1}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			indexes := re.FindSubmatchIndex([]byte(item.input))
+
+			if !slices.Equal(indexes, item.indexes) {
+				t.Errorf("Expected indexes %v, got: %v", item.indexes, indexes)
+			}
+			if re.NumSubexp() != len(item.indexes)/2-1 && item.indexes != nil {
+				t.Errorf("Expected %v groups, got: %v", len(item.indexes)/2-1, re.NumSubexp())
+			}
+		})
+	}
+}
+
+func TestCountedRepeatSubmatches(t *testing.T) {
+	data := []Data{
+		{pattern: "(a){2}", input: "aa"},
+		{pattern: "b{2,}(a?){1,2}", input: "a bb"},
+		{pattern: "(a|b){2,3}c", input: "abbc ac"},
+		{pattern: "((a)b){2,}", input: "ababab"},
+		{pattern: "(?:(x)|(y)){1,3}z", input: "xyz yyxz"},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			expected := regexp.MustCompile(item.pattern).FindAllSubmatchIndex([]byte(item.input), -1)
+			indexes := MustCompile(item.pattern).FindAllSubmatchIndex([]byte(item.input), -1)
+
+			if !slices.EqualFunc(indexes, expected, slices.Equal) {
+				t.Errorf("Expected the same indexes as regexp: %v, got: %v", expected, indexes)
+			}
+		})
+	}
+}
+
+func TestFindAllSubmatch(t *testing.T) {
+	re := MustCompile("(\\w+)=(\\d+)?")
+	input := []byte("a=1 b= c=33")
+
+	indexes := re.FindAllSubmatchIndex(input, -1)
+	expectedIndexes := [][]int{{0, 3, 0, 1, 2, 3}, {4, 6, 4, 5, -1, -1}, {7, 11, 7, 8, 9, 11}}
+	if !slices.EqualFunc(indexes, expectedIndexes, slices.Equal) {
+		t.Errorf("Expected indexes %v, got: %v", expectedIndexes, indexes)
+	}
+
+	submatches := re.FindAllSubmatch(input, -1)
+	if len(submatches) != 3 || string(submatches[2][2]) != "33" || submatches[1][2] != nil {
+		t.Errorf("Unexpected submatches: %q", submatches)
+	}
+
+	locs := re.FindAllIndex(input, 2)
+	if !slices.EqualFunc(locs, [][]int{{0, 3}, {4, 6}}, slices.Equal) {
+		t.Errorf("Expected the first two match bounds, got: %v", locs)
+	}
+	if loc := re.FindIndex(input); !slices.Equal(loc, []int{0, 3}) {
+		t.Errorf("Expected [0 3], got: %v", loc)
+	}
+}
+
+func TestNonCapturingGroup(t *testing.T) {
+	data := []struct {
+		pattern string
+		input   string
+		indexes []int
+	}{
+		{pattern: "(?:ab)+", input: "xababc", indexes: []int{1, 5}},
+		{pattern: "(?:cat|dog)s", input: "dogs", indexes: []int{0, 4}},
+		{pattern: "(?:a(b))(c)", input: "abc", indexes: []int{0, 3, 1, 2, 2, 3}},
+		{pattern: "((?:a|b)+)-(?:\\d)", input: "ab-1", indexes: []int{0, 4, 0, 2}},
+		{pattern: "(?:x)(\\w) \\1", input: "xa a", indexes: []int{0, 4, 1, 2}},
+		{pattern: "(?:(?:a)(b))\\1", input: "abb", indexes: []int{0, 3, 1, 2}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			indexes := re.FindSubmatchIndex([]byte(item.input))
+
+			if !slices.Equal(indexes, item.indexes) {
+				t.Errorf("Expected indexes %v, got: %v", item.indexes, indexes)
+			}
+		})
+	}
+}
+
+func TestGraphGroupLabels(t *testing.T) {
+	groups := []string{}
+	for _, state := range MustCompile("(?:a(b))c").Graph().States {
+		if state.Groups != "" {
+			groups = append(groups, state.Groups)
+		}
+	}
+	slices.Sort(groups)
+
+	if !stringSliceEqual(groups, []string{"(1", "1)"}) {
+		t.Errorf("Expected only group 1 to be marked, got: %v", groups)
+	}
+}
+
+func TestNamedGroups(t *testing.T) {
+	data := []struct {
+		pattern string
+		input   string
+		indexes []int
+	}{
+		{pattern: "(?P<year>\\d+)-(?P<month>\\d+)", input: "on 2024-05", indexes: []int{3, 10, 3, 7, 8, 10}},
+		{pattern: "(?<word>\\w+) \\k<word>", input: "the the", indexes: []int{0, 7, 0, 3}},
+		{pattern: "(?P<a>x)(y)\\k<a>\\2", input: "xyxy", indexes: []int{0, 4, 0, 1, 1, 2}},
+		{pattern: "(a)(b)(c)(d)(e)(f)(g)(h)(i)(j)(k)(l)\\12", input: "abcdefghijkll", indexes: []int{0, 13, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12}},
+		{pattern: "(a)\\10", input: "aa0", indexes: []int{0, 3, 0, 1}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			indexes := re.FindSubmatchIndex([]byte(item.input))
+
+			if !slices.Equal(indexes, item.indexes) {
+				t.Errorf("Expected indexes %v, got: %v", item.indexes, indexes)
+			}
+		})
+	}
+}
+
+func TestSubexpNames(t *testing.T) {
+	re := MustCompile("(?P<key>\\w+)=(\\w+)(?:;(?<comment>.*))?")
+
+	names := re.SubexpNames()
+	if !stringSliceEqual(names, []string{"", "key", "", "comment"}) {
+		t.Errorf("Unexpected group names: %q", names)
+	}
+	if re.NumSubexp() != 3 {
+		t.Errorf("Expected 3 groups, got: %v", re.NumSubexp())
+	}
+	if i := re.SubexpIndex("comment"); i != 3 {
+		t.Errorf("Expected comment to be group 3, got: %v", i)
+	}
+	if i := re.SubexpIndex("missing"); i != -1 {
+		t.Errorf("Expected -1 for an unknown name, got: %v", i)
+	}
+	match := re.FindSubmatch([]byte("a=b;note"))
+	if string(match[re.SubexpIndex("comment")]) != "note" {
+		t.Errorf("Expected comment note, got: %q", match)
+	}
+}
+
+func TestNamedGroupErrors(t *testing.T) {
+	patterns := []string{"(?P<a>x)(?P<a>y)", "(?P<>x)", "(?P<a-b>x)", "(?P<a x)", "(x)\\k<y>", "\\kx"}
+
+	for _, pattern := range patterns {
+		t.Run(fmt.Sprintf("Checking pattern %v", pattern), func(t *testing.T) {
+			if _, err := Compile(pattern); err == nil {
+				t.Errorf("Expected an error for %v", pattern)
+			}
+		})
+	}
+}
+
+func TestLazyQuantifier(t *testing.T) {
+	data := []Data{
+		{pattern: "<.+?>", input: "<b>bold</b>", matches: []string{"<b>", "</b>"}},
+		{pattern: "<.+>", input: "<b>bold</b>", matches: []string{"<b>bold</b>"}},
+		{pattern: "a*?b", input: "aaab", matches: []string{"aaab"}},
+		{pattern: "xa*?", input: "xaa", matches: []string{"x"}},
+		{pattern: "xa??", input: "xa", matches: []string{"x"}},
+		{pattern: "xa{2,4}?", input: "xaaaa", matches: []string{"xaa"}},
+		{pattern: "xa{2,}?", input: "xaaaa", matches: []string{"xaa"}},
+		{pattern: "xa{2}?", input: "xaaaa", matches: []string{"xaa"}},
+		{pattern: "(\\w+?)\\1", input: "abab", matches: []string{"abab"}},
+		{pattern: "xa{0,2}", input: "x xa xaaa", matches: []string{"x", "xa", "xaa"}},
+		{pattern: "xa{0,}", input: "x xaaa", matches: []string{"x", "xaaa"}},
+		{pattern: "xa{0,}?", input: "x xaaa", matches: []string{"x", "x"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			if !strings.Contains(item.pattern, "\\1") {
+				expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+				if !stringSliceEqual(matches, expected) {
+					t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+				}
+			}
+		})
+	}
+}
+
+func TestAtomicAndPossessive(t *testing.T) {
+	data := []Data{
+		{pattern: "a*+a", input: "aaaa", matches: []string{}},
+		{pattern: "a++b", input: "aaab", matches: []string{"aaab"}},
+		{pattern: "a?+a", input: "a", matches: []string{}},
+		{pattern: "xa{1,3}+a", input: "xaaaa", matches: []string{"xaaaa"}},
+		{pattern: "xa{1,3}+a", input: "xaaa", matches: []string{}},
+		{pattern: "(?>ab|a)b", input: "ab", matches: []string{}},
+		{pattern: "(?>a|ab)b", input: "ab", matches: []string{"ab"}},
+		{pattern: "(?>\\w+)\\d", input: "abc1", matches: []string{}},
+		{pattern: "(?>x+)y|xx", input: "xxx", matches: []string{"xx"}},
+		{pattern: "((?>a+))b\\1", input: "aabaa", matches: []string{"aabaa"}},
+		{pattern: "(?>(\\w+?)c)", input: "abc", matches: []string{"abc"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+		})
+	}
+
+	re := MustCompile("(?<word>(?>\\w+)) (\\w+)")
+	if indexes := re.FindSubmatchIndex([]byte("hi there")); !slices.Equal(indexes, []int{0, 8, 0, 2, 3, 8}) {
+		t.Errorf("Expected captures inside and after the atomic group, got: %v", indexes)
+	}
+}
+
+func TestAtomicNoCatastrophicBacktracking(t *testing.T) {
+	// without the atomic groups these take exponential time
+	input := strings.Repeat("a", 1000) + "!"
+
+	for _, pattern := range []string{"(?>a+)+b", "(a++)+b", "(?>(a|aa)+)+c\\1"} {
+		if MustCompile(pattern).MatchString(input) {
+			t.Errorf("Expected %v not to match", pattern)
+		}
+	}
+}
+
+func TestBacktrackingIsBounded(t *testing.T) {
+	// the atomic group or backreference sends the whole pattern to the
+	// backtracker, trying every way to split the b's takes exponential time
+	data := []Data{
+		{pattern: "(?:b.*)*c|(?>a)", input: strings.Repeat("b", 30), matches: []string{}},
+		{pattern: "(?>(?:b.*)*c)", input: strings.Repeat("b", 30), matches: []string{}},
+		{pattern: "(b+)+c|x\\1", input: strings.Repeat("b", 30) + " xx", matches: []string{}},
+		{pattern: "(?:a|b)*+(?:b.*)*c|(?>a)", input: "x" + strings.Repeat("b", 200), matches: []string{}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			start := time.Now()
+			matches := MustCompile(item.pattern).FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected the search to take less than a second, took: %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestLookaround(t *testing.T) {
+	data := []Data{
+		{pattern: "\\d+(?!\\d|ms)", input: "took 15ms, 20s and 7", matches: []string{"20", "7"}},
+		{pattern: "\\d+(?=s)", input: "took 15ms, 20s", matches: []string{"20"}},
+		{pattern: "(?<=USD)\\d+", input: "cost USD30 or 40", matches: []string{"30"}},
+		{pattern: "(?<!USD|\\d)\\d+", input: "cost USD30 or 40", matches: []string{"40"}},
+		{pattern: "(?<=ab|b)c", input: "abc bc xc", matches: []string{"c", "c"}},
+		{pattern: "(?<=a\\w*)z", input: "a12z xz", matches: []string{"z"}},
+		{pattern: "(?<=^)a", input: "aa", matches: []string{"a"}},
+		{pattern: "(?<=a$)b", input: "ab", matches: []string{}},
+		{pattern: "(?<=a\\b)b", input: "ab", matches: []string{}},
+		{pattern: "(?<=a\\B)b", input: "ab", matches: []string{"b"}},
+		{pattern: "(?<=a(?=b))b", input: "ab ac", matches: []string{"b"}},
+		{pattern: "(?<=a$)", input: "ba", matches: []string{""}},
+		{pattern: "q(?=u)", input: "quit qat", matches: []string{"q"}},
+		{pattern: "(?=(\\w))\\1\\1", input: "abb", matches: []string{"bb"}},
+		{pattern: "(?=(a+))a*b\\1", input: "aaba", matches: []string{"aba"}},
+		{pattern: "(\\w)(?=\\1)", input: "aabb", matches: []string{"a", "b"}},
+		{pattern: "(?!x)\\w", input: "xy", matches: []string{"y"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := Compile(item.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+		})
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	data := map[string]int{"ab|c": 2, "a?b": 2, "a{2,3}": 3, "(?=abc)a": 1, "a*": -1, "(a)\\1": -1}
+
+	for pattern, expected := range data {
+		if length := MustCompile(pattern).prog.maxLength(); length != expected {
+			t.Errorf("Expected %v to match at most %v bytes, got: %v", pattern, expected, length)
+		}
+	}
+}
+
+func TestWordBoundaryAndTextAnchors(t *testing.T) {
+	data := []Data{
+		{pattern: "\\bcat\\b", input: "cat concat cats cat", matches: []string{"cat", "cat"}},
+		{pattern: "\\Bcat", input: "cat concat", matches: []string{"cat"}},
+		{pattern: "\\b", input: "ab c", matches: []string{"", "", "", ""}},
+		{pattern: "\\B", input: "ab c", matches: []string{""}},
+		{pattern: "\\b\\d+\\b", input: "a1 22 3b", matches: []string{"22"}},
+		{pattern: "\\Aab", input: "abab", matches: []string{"ab"}},
+		{pattern: "ab\\z", input: "abab", matches: []string{"ab"}},
+		{pattern: "ab\\z", input: "ab\n", matches: []string{}},
+		{pattern: "ab\\Z", input: "ab\n", matches: []string{"ab"}},
+		{pattern: "b|\\Aa", input: "aab", matches: []string{"a", "b"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			// \Z isn't supported by the standard library
+			if !strings.Contains(item.pattern, "\\Z") {
+				expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+				if !stringSliceEqual(matches, expected) {
+					t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+				}
+			}
+		})
+	}
+}
+
+func TestWholeWord(t *testing.T) {
+	data := []Data{
+		{pattern: "cat", input: "cat concat cats cat", matches: []string{"cat", "cat"}},
+		{pattern: "cat|dog", input: "dogma cat hotdog dog", matches: []string{"cat", "dog"}},
+		{pattern: "(a)|b", input: "a ab b", matches: []string{"a", "b"}},
+		// stricter than \b: the match can't touch a word character at all
+		{pattern: "-x", input: "a-x -x", matches: []string{"-x"}},
+		{pattern: "\\d+", input: "12 a3 45b 6", matches: []string{"12", "6"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := CompileWithOptions(item.pattern, Options{WholeWord: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+		})
+	}
+
+	// the pattern can't close the groups around it, it's invalid on its own
+	for _, pattern := range []string{"a)|(b", "a)(?:b", ")"} {
+		_, err := CompileWithOptions(pattern, Options{WholeWord: true})
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected a syntax error for %v, got: %v", pattern, err)
+		}
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	data := []Data{
+		{pattern: "\\s+", input: "a \t\nb", matches: []string{" \t\n"}},
+		{pattern: "\\S+", input: "ab c", matches: []string{"ab", "c"}},
+		{pattern: "\\D+", input: "ab12c", matches: []string{"ab", "c"}},
+		{pattern: "\\W", input: "a_b-c", matches: []string{"-"}},
+		{pattern: "[\\s\\d]+", input: "a1 2b", matches: []string{"1 2"}},
+		{pattern: "[^\\W]+", input: "ab-cd", matches: []string{"ab", "cd"}},
+		{pattern: "[\\D]", input: "1a", matches: []string{"a"}},
+		{pattern: "a\\tb", input: "a\tb", matches: []string{"a\tb"}},
+		{pattern: "[\\r\\n\\f\\v]", input: "a\r\n\f\vb", matches: []string{"\r", "\n", "\f", "\v"}},
+		{pattern: "\\x41\\x{42}", input: "zABz", matches: []string{"AB"}},
+		{pattern: "[\\x30-\\x32]+", input: "0123", matches: []string{"012"}},
+		{pattern: "\\x{105}", input: "zą", matches: []string{"ą"}},
+		{pattern: "[\\x{105}-\\x{107}]", input: "ząć", matches: []string{"ą", "ć"}},
+		{pattern: "\\Qa.b*\\E", input: "a.b* axbb", matches: []string{"a.b*"}},
+		{pattern: "\\Qab\\E+", input: "abbb", matches: []string{"abbb"}},
+		{pattern: "\\Q(a|b)", input: "x(a|b)", matches: []string{"(a|b)"}},
+		{pattern: "[\\Q]-\\E]+", input: "a]-]b", matches: []string{"]-]"}},
+		{pattern: "\\.\\$\\(\\\\", input: "x.$(\\", matches: []string{".$(\\"}},
+		{pattern: "[a\\-z]+", input: "b-az", matches: []string{"-az"}},
+		{pattern: "\\d", input: "a5", matches: []string{"5"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := Compile(item.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			// the standard library doesn't allow \Q...\E inside brackets
+			if !strings.Contains(item.pattern, "[\\Q") {
+				expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+				if !stringSliceEqual(matches, expected) {
+					t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+				}
+			}
+		})
+	}
+
+	octal := MustCompile("\\077[\\101\\102]")
+	if !octal.MatchString("?A") || !octal.MatchString("?B") || octal.MatchString("?C") {
+		t.Errorf("Expected octal escapes to match ? followed by A or B")
+	}
+}
+
+func TestEscapeErrors(t *testing.T) {
+	patterns := []string{"a\\", "\\y", "\\e", "[\\y]", "\\xZZ", "\\x{110000}", "\\x{41", "[z-a]", "[ab"}
+
+	for _, pattern := range patterns {
+		t.Run(fmt.Sprintf("Checking pattern %v", pattern), func(t *testing.T) {
+			if _, err := Compile(pattern); err == nil {
+				t.Errorf("Expected an error for %v", pattern)
+			}
+		})
+	}
+}
+
+func TestUTF8(t *testing.T) {
+	data := []Data{
+		{pattern: ".", input: "łód", matches: []string{"ł", "ó", "d"}},
+		{pattern: "^..$", input: "日本", matches: []string{"日本"}},
+		{pattern: "[ą-ż]+", input: "zażółć gęślą", matches: []string{"ż", "łć", "ęś", "ą"}},
+		{pattern: "[^a ]+", input: "aźdźbło a", matches: []string{"źdźbło"}},
+		{pattern: "[\\x{105}ł]", input: "łabą", matches: []string{"ł", "ą"}},
+		{pattern: "語+", input: "日本語語", matches: []string{"語語"}},
+		{pattern: "日本|語", input: "日本語", matches: []string{"日本", "語"}},
+		{pattern: "\\W", input: "aé", matches: []string{"é"}},
+		{pattern: "x*", input: "ąx", matches: []string{"", "x"}},
+		{pattern: "(?<=ł)ó", input: "łódó", matches: []string{"ó"}},
+		{pattern: "(ó)\\1", input: "óóx", matches: []string{"óó"}},
+		// invalid UTF-8 reads one utf8.RuneError per byte
+		{pattern: "a.b", input: "a\xffb a\xe2\x82b", matches: []string{"a\xffb"}},
+		{pattern: "[^a]+", input: "\xe2\x82a", matches: []string{"\xe2\x82"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			matches := MustCompile(item.pattern).FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			// the standard library doesn't support backreferences and
+			// lookarounds
+			if !strings.Contains(item.pattern, "\\1") && !strings.Contains(item.pattern, "(?<") {
+				expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+				if !stringSliceEqual(matches, expected) {
+					t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+				}
+			}
+		})
+	}
+
+	if _, err := Compile("a\xff"); err == nil {
+		t.Errorf("Expected an error for a pattern which isn't valid UTF-8")
+	}
+}
+
+func TestUnicodeClasses(t *testing.T) {
+	data := []Data{
+		{pattern: "\\p{L}+", input: "zażółć 123 日本", matches: []string{"zażółć", "日本"}},
+		{pattern: "\\pL+", input: "ab1", matches: []string{"ab"}},
+		{pattern: "\\p{Lu}", input: "aŁbΩ", matches: []string{"Ł", "Ω"}},
+		{pattern: "\\p{Greek}+", input: "abc αβγ", matches: []string{"αβγ"}},
+		{pattern: "\\p{Han}+", input: "日本語とかな", matches: []string{"日本語"}},
+		{pattern: "\\P{N}+", input: "ab12c", matches: []string{"ab", "c"}},
+		{pattern: "\\p{^L}+", input: "ab12c", matches: []string{"12"}},
+		{pattern: "[\\p{Lu}\\d]+", input: "aB1Ćd", matches: []string{"B1Ć"}},
+		{pattern: "[^\\p{L}\\s]+", input: "ab, cd!", matches: []string{",", "!"}},
+		{pattern: "[\\P{L}]", input: "ał1", matches: []string{"1"}},
+		{pattern: "^\\p{Any}$", input: "語", matches: []string{"語"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			matches := MustCompile(item.pattern).FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+			if !stringSliceEqual(matches, expected) {
+				t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"\\p", "\\p{L", "\\p{Klingon}", "[\\p{Foo}]"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Expected an error for %v", pattern)
+		}
+	}
+}
+
+func TestPosixClasses(t *testing.T) {
+	data := []Data{
+		{pattern: "[[:digit:]]+", input: "ab123c4", matches: []string{"123", "4"}},
+		{pattern: "[^[:space:]]+", input: "ab \tc", matches: []string{"ab", "c"}},
+		{pattern: "[[:alpha:]_][[:alnum:]_]*", input: "1 foo_2 _x", matches: []string{"foo_2", "_x"}},
+		{pattern: "[[:upper:][:digit:]]+", input: "aB1c", matches: []string{"B1"}},
+		{pattern: "[[:lower:]]+", input: "aBc", matches: []string{"a", "c"}},
+		{pattern: "[[:blank:]]", input: "a b\tc\n", matches: []string{" ", "\t"}},
+		{pattern: "[[:cntrl:]]", input: "a\x01b\x7f", matches: []string{"\x01", "\x7f"}},
+		{pattern: "[[:punct:]]+", input: "a,.!b", matches: []string{",.!"}},
+		{pattern: "[[:graph:]]+", input: "ab c", matches: []string{"ab", "c"}},
+		{pattern: "[[:print:]]+", input: "ab c\x01", matches: []string{"ab c"}},
+		{pattern: "[[:xdigit:]]+", input: "0xBEEFg", matches: []string{"0", "BEEF"}},
+		{pattern: "[[:^digit:]]+", input: "ab1", matches: []string{"ab"}},
+		{pattern: "[[a]+", input: "b[a", matches: []string{"[a"}},
+		{pattern: "([[:alpha:](])+", input: "1a(b", matches: []string{"a(b"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := Compile(item.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+			if !stringSliceEqual(matches, expected) {
+				t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"[[:foo:]]", "[[=a=]]", "[[.a.]]", "[[:alpha:]"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Expected an error for %v", pattern)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	data := []Data{
+		{pattern: "(?i)abc", input: "xAbC abc", matches: []string{"AbC", "abc"}},
+		{pattern: "(?i:a)b", input: "Ab AB ab", matches: []string{"Ab", "ab"}},
+		{pattern: "a(?i)b|c", input: "aB C c", matches: []string{"aB", "C", "c"}},
+		{pattern: "((?i)a)a", input: "AaAA", matches: []string{"Aa"}},
+		{pattern: "(?i)a(?-i)b", input: "AB Ab", matches: []string{"Ab"}},
+		{pattern: "(?i)k", input: "K k \u212a", matches: []string{"K", "k", "\u212a"}},
+		{pattern: "(?i)ą+", input: "ĄąA", matches: []string{"Ąą"}},
+		{pattern: "(?i)[a-c]+", input: "xAbC", matches: []string{"AbC"}},
+		{pattern: "(?i)[^k]", input: "kK\u212ax", matches: []string{"x"}},
+		{pattern: "(?i)\\w+", input: "aB_\u212a", matches: []string{"aB_\u212a"}},
+		{pattern: "(?i)\\p{Lu}+", input: "aB1", matches: []string{"aB"}},
+		{pattern: "(?i)[[:upper:]]+", input: "aB1", matches: []string{"aB"}},
+		{pattern: "(?i)\\x41", input: "a", matches: []string{"a"}},
+		{pattern: "a.b", input: "a\nb axb", matches: []string{"axb"}},
+		{pattern: "(?s)a.b", input: "a\nb", matches: []string{"a\nb"}},
+		{pattern: "(?m)^\\w+$", input: "ab\ncd", matches: []string{"ab", "cd"}},
+		{pattern: "^\\w+$", input: "ab\ncd", matches: nil},
+		{pattern: "(?U)a+", input: "aaa", matches: []string{"a", "a", "a"}},
+		{pattern: "(?U)a+?", input: "aaa", matches: []string{"aaa"}},
+		{pattern: "(?U:a*)a", input: "aaa", matches: []string{"a", "a", "a"}},
+		{pattern: "(?ims)^a.$", input: "x\nA\n", matches: []string{"A\n"}},
+		{pattern: "(?i:^a.)", input: "Ab\nAb", matches: []string{"Ab"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %q, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := Compile(item.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+			if !stringSliceEqual(matches, expected) {
+				t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+			}
+		})
+	}
+
+	if !MustCompile("(?i)(a)\\1").MatchString("aA") || !MustCompile("(?i)(\\w+) \\1").MatchString("Kot kOT") {
+		t.Errorf("Expected case insensitive backreferences to match")
+	}
+	if MustCompile("(a)(?i)\\1").MatchString("aA") == false || MustCompile("(?i:(a))\\1").MatchString("aA") {
+		t.Errorf("Expected backreferences to follow the flags where they are")
+	}
+
+	for _, pattern := range []string{"(?x)", "(?)", "(?-)", "(?i-)", "(?i", "(?i-m-s)", "(?i:a"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Expected an error for %v", pattern)
+		}
+	}
+}
+
+func TestCaseInsensitiveOption(t *testing.T) {
+	for _, pattern := range []string{"cat|dog", "c[a-z]t", "CAT"} {
+		re, err := CompileWithOptions(pattern, Options{CaseInsensitive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !re.MatchString("a Cat") {
+			t.Errorf("Expected %v to match case insensitively", pattern)
+		}
+	}
+
+	re, err := CompileWithOptions("(?-i)cat", Options{CaseInsensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.MatchString("CAT") {
+		t.Errorf("Expected (?-i) to turn the option off")
+	}
+}
+
+func TestMultiLine(t *testing.T) {
+	data := []Data{
+		{pattern: "^\\w+$", input: "ab\ncd\n", matches: []string{"ab", "cd"}},
+		{pattern: "^$", input: "a\n\nb", matches: []string{""}},
+		{pattern: "^", input: "a\nb", matches: []string{"", ""}},
+		{pattern: "$", input: "a\nb", matches: []string{"", ""}},
+		{pattern: "b$\n^c", input: "ab\ncd", matches: []string{"b\nc"}},
+		{pattern: "\\A\\w", input: "ab\ncd", matches: []string{"a"}},
+		{pattern: "(?-m)^\\w", input: "ab\ncd", matches: []string{"a"}},
+		{pattern: "^error: .*$", input: "ok\nerror: disk full\nok", matches: []string{"error: disk full"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %q, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := CompileWithOptions(item.pattern, Options{MultiLine: true})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+			expected := regexp.MustCompile("(?m)"+item.pattern).FindAllString(item.input, -1)
+			if !stringSliceEqual(matches, expected) {
+				t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+			}
+		})
+	}
+}
+
+func TestStartAnchored(t *testing.T) {
+	data := []struct {
+		pattern  string
+		anchored bool
+	}{
+		{pattern: "^a", anchored: true},
+		{pattern: "(^a|^b)c", anchored: true},
+		{pattern: "\\Aab", anchored: true},
+		{pattern: "^a|b", anchored: false},
+		{pattern: "(^a|b)", anchored: false},
+		{pattern: "x|^y", anchored: false},
+		{pattern: "(?m)^a", anchored: false},
+		{pattern: "a^", anchored: false},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			if re.prog.anchoredStart != item.anchored {
+				t.Errorf("Expected anchored to be %v", item.anchored)
+			}
+
+			input := "xyab ab yb"
+			matches := re.FindAllString(input, -1)
+			expected := regexp.MustCompile(item.pattern).FindAllString(input, -1)
+			if !stringSliceEqual(matches, expected) {
+				t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, matches)
+			}
+		})
+	}
+
+	if !MustCompile("^a|b").MatchString("xb") {
+		t.Errorf("Expected ^a|b to match b anywhere")
+	}
+}
+
+func TestBytesMode(t *testing.T) {
+	data := []Data{
+		{pattern: "^.$", input: "ą", matches: nil},
+		{pattern: "^..$", input: "ą", matches: []string{"ą"}},
+		{pattern: "\\xff+", input: "a\xff\xffb", matches: []string{"\xff\xff"}},
+		{pattern: "[\\x80-\\xff]+", input: "aąb", matches: []string{"ą"}},
+		{pattern: "a\xc4", input: "a\xc4\x85", matches: []string{"a\xc4"}},
+		{pattern: "\\x{105}", input: "zą", matches: []string{"ą"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := CompileWithOptions(item.pattern, Options{Bytes: true})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+		})
+	}
+
+	if _, err := CompileWithOptions("[\\x{105}]", Options{Bytes: true}); err == nil {
+		t.Errorf("Expected an error for a character above 0xFF in brackets")
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	data := []struct {
+		pattern  string
+		kind     ErrorKind
+		offset   int
+		fragment string
+	}{
+		{pattern: "ab(cd", kind: ErrMissingParen, offset: 2, fragment: "(cd"},
+		{pattern: "ab)cd", kind: ErrUnexpectedParen, offset: 2, fragment: ")"},
+		{pattern: "x[ab", kind: ErrMissingBracket, offset: 1, fragment: "[ab"},
+		{pattern: "[a-cz-a]", kind: ErrInvalidCharRange, offset: 4, fragment: "z-a"},
+		{pattern: "ab\\", kind: ErrTrailingBackslash, offset: 2, fragment: "\\"},
+		{pattern: "a\\q", kind: ErrInvalidEscape, offset: 1, fragment: "\\q"},
+		{pattern: "a\\x{zz}", kind: ErrInvalidEscape, offset: 1, fragment: "\\x{zz}"},
+		{pattern: "\\ą|x", kind: ErrInvalidEscape, offset: 0, fragment: "\\ą"},
+		{pattern: "[[:foo:]]", kind: ErrInvalidCharClass, offset: 1, fragment: "[:foo:]"},
+		{pattern: "\\p{Foo}", kind: ErrInvalidCharClass, offset: 0, fragment: "\\p{Foo}"},
+		{pattern: "[[=a=]]", kind: ErrUnsupportedCharClass, offset: 1, fragment: "[=a=]"},
+		{pattern: "*a", kind: ErrMissingRepeatArgument, offset: 0, fragment: "*"},
+		{pattern: "a|{2}", kind: ErrMissingRepeatArgument, offset: 2, fragment: "{2}"},
+		{pattern: "a**", kind: ErrInvalidNestedRepeat, offset: 1, fragment: "**"},
+		{pattern: "a{2}{3}", kind: ErrInvalidNestedRepeat, offset: 1, fragment: "{2}{3}"},
+		{pattern: "a{3,1}", kind: ErrInvalidRepeatSize, offset: 1, fragment: "{3,1}"},
+		{pattern: "a{1001}", kind: ErrInvalidRepeatSize, offset: 1, fragment: "{1001}"},
+		{pattern: "((a{100}){100}){100}", kind: ErrExpressionTooLarge, offset: 15, fragment: "{100}"},
+		{pattern: "(?P<a>x)(?P<a>y)", kind: ErrInvalidNamedCapture, offset: 8, fragment: "(?P<a>"},
+		{pattern: "(?P<a-b>x)", kind: ErrInvalidNamedCapture, offset: 0, fragment: "(?P<a-b>"},
+		{pattern: "(x)\\k<y>", kind: ErrInvalidBackreference, offset: 3, fragment: "\\k<y>"},
+		{pattern: "\\1", kind: ErrInvalidBackreference, offset: 0, fragment: "\\1"},
+		{pattern: "(a)\\2", kind: ErrInvalidBackreference, offset: 3, fragment: "\\2"},
+		{pattern: "x\\8", kind: ErrInvalidBackreference, offset: 1, fragment: "\\8"},
+		{pattern: "a(?z)", kind: ErrInvalidFlags, offset: 1, fragment: "(?z"},
+		{pattern: "ą\xff", kind: ErrInvalidUTF8, offset: 2, fragment: "\xff"},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			_, err := Compile(item.pattern)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a syntax error, got: %v", err)
+			}
+			if syntaxErr.Kind != item.kind || syntaxErr.Offset != item.offset || syntaxErr.Fragment != item.fragment {
+				t.Errorf("Expected %v at %v: %q, got: %v at %v: %q", item.kind, item.offset, item.fragment, syntaxErr.Kind, syntaxErr.Offset, syntaxErr.Fragment)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorCaret(t *testing.T) {
+	data := []struct {
+		pattern string
+		caret   string
+	}{
+		{pattern: "a{3,1}", caret: "a{3,1}\n ^~~~~"},
+		{pattern: "żółw)", caret: "żółw)\n    ^"},
+		{pattern: "\ta[z-a]", caret: "\ta[z-a]\n\t  ^~~"},
+		{pattern: "(?m)a\nb\\", caret: "b\\\n ^"},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking pattern %v", item.pattern), func(t *testing.T) {
+			_, err := Compile(item.pattern)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a syntax error, got: %v", err)
+			}
+			if caret := syntaxErr.Caret(); caret != item.caret {
+				t.Errorf("Expected caret:\n%v\ngot:\n%v", item.caret, caret)
+			}
+		})
+	}
+}
+
+func TestEmptyAlternatives(t *testing.T) {
+	data := []Data{
+		{pattern: "", input: "ab", matches: []string{"", "", ""}},
+		{pattern: "a|", input: "ab", matches: []string{"a", ""}},
+		{pattern: "(|a)b", input: "ab", matches: []string{"ab"}},
+		{pattern: "x()y", input: "xy", matches: []string{"xy"}},
+		{pattern: "a{,2}", input: "a{,2}", matches: []string{"a{,2}"}},
+		{pattern: "a{x", input: "a{x", matches: []string{"a{x"}},
+		{pattern: "{", input: "a{", matches: []string{"{"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := Compile(item.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+			expected := regexp.MustCompile(item.pattern).FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) || !stringSliceEqual(matches, expected) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+		})
+	}
+}
+
+func TestEmptyLoopsBacktracking(t *testing.T) {
+	data := []Data{
+		{pattern: "(a*)*+b", input: "aab", matches: []string{"aab"}},
+		{pattern: "(?>(a|)*)b", input: "ab b", matches: []string{"ab", "b"}},
+		{pattern: "^*+x", input: "x", matches: []string{"x"}},
+		{pattern: "(a*)(\\1)*b", input: "aab", matches: []string{"aab"}},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re, err := Compile(item.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := re.FindAllString(item.input, -1)
+
+			if !stringSliceEqual(matches, item.matches) {
+				t.Errorf("Expected to find these matches: %v, got: %v", item.matches, matches)
+			}
+		})
+	}
+}
+
+func TestEmptyBodyLoops(t *testing.T) {
+	data := []Data{
+		{pattern: "a?((?:[^a]*?))*c", input: "c1c c1b"},
+		{pattern: "(a*)*", input: "b"},
+		{pattern: "(a|b*)*c", input: "abbc ac"},
+		{pattern: "(?:a?)*?b", input: "aab"},
+		{pattern: "(a?)*?(b*)*", input: "aabb"},
+		{pattern: "(x|)*y", input: "xxy y"},
+		{pattern: "(\\b|a)*b", input: "aab"},
+		{pattern: "(a*?)*?c", input: "aac"},
+		{pattern: "((a)|b*)*c", input: "abac"},
+	}
+
+	for _, item := range data {
+		t.Run(fmt.Sprintf("Checking input %v, for pattern %v", item.input, item.pattern), func(t *testing.T) {
+			re := MustCompile(item.pattern)
+			expected := regexp.MustCompile(item.pattern).FindAllSubmatchIndex([]byte(item.input), -1)
+			indexes := re.FindAllSubmatchIndex([]byte(item.input), -1)
+
+			if fmt.Sprint(indexes) != fmt.Sprint(expected) {
+				t.Errorf("Expected the same matches as regexp: %v, got: %v", expected, indexes)
+			}
+			for i := 0; i <= len(item.input); i++ {
+				if caps, backtracked := re.prog.search([]byte(item.input), i, true), re.prog.run([]byte(item.input), i); !slices.Equal(caps, backtracked) {
+					t.Errorf("At %v expected backtracking result %v, got: %v", i, backtracked, caps)
+				}
+			}
+		})
+	}
+}
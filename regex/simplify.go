@@ -0,0 +1,340 @@
+package regex
+
+import (
+	"reflect"
+	"slices"
+)
+
+// ------------------ Simplify ------------------
+// Rewrites a tree into a smaller one matching the same strings, with the
+// same submatches, before it's compiled. Patterns generated by programs,
+// e.g. a list of words joined by '|', shrink a lot:
+//
+//   - runs of literals become a Text, compiled into a single chain of states
+//   - single character alternatives become a class: a|b|[cd] → [a-d]
+//   - alternatives starting the same way are factored: foo|foobar →
+//     foo(?:|bar), printed foo(?:bar)?? since the empty alternative comes
+//     first. Only prefixes of fixed width are factored, so that the
+//     alternatives are still tried in the same order.
+//   - nested quantifiers collapse: (?:a*)* → a*, (?:a+)? → a*
+//   - empty groups and alternatives go away: a(?:)b → ab, (?:)* → (?:)
+//
+// Capturing groups are left in place, (a*)* isn't the same as a* since the
+// group captures the last iteration.
+// ---------------------------------------------
+
+// Simplify returns a tree matching the same strings as n, with the rewrites
+// above applied. n is read as UTF-8, see simplifier.decoder.
+func Simplify(n Node) Node {
+	return simplifier{}.run(n)
+}
+
+type simplifier struct {
+	// which characters fit in a class and how they fold, like the compiler's
+	decoder decoder
+}
+
+func (s simplifier) run(n Node) Node {
+	return mergeLiterals(s.simplify(n))
+}
+
+func (s simplifier) simplify(n Node) Node {
+	switch n := n.(type) {
+	case Group:
+		n.Sub = s.simplify(n.Sub)
+		if n.Atomic && n.Sub == (Empty{}) {
+			return Empty{}
+		}
+		return n
+	case Lookaround:
+		n.Sub = s.simplify(n.Sub)
+		// (?=) and (?<=) always match
+		if !n.Negative && n.Sub == (Empty{}) {
+			return Empty{}
+		}
+		return n
+	case Repeat:
+		return s.repeat(n)
+	case Concat:
+		return s.concat(n)
+	case Alternate:
+		return s.alternate(n)
+	}
+
+	return n
+}
+
+func (s simplifier) repeat(n Repeat) Node {
+	n.Sub = s.simplify(n.Sub)
+	return s.repeatOf(n)
+}
+
+// repeatOf simplifies n, its Sub being simplified already.
+func (s simplifier) repeatOf(n Repeat) Node {
+	switch {
+	case n.Sub == (Empty{}):
+		return Empty{}
+	case n.Min == 1 && n.Max == 1 && !n.Possessive:
+		return n.Sub
+	}
+
+	inner, ok := n.Sub.(Repeat)
+	if !ok || !isSimpleRepeat(n) || !isSimpleRepeat(inner) || n.Lazy != inner.Lazy {
+		return n
+	}
+	// a repeat of a repeat, both being *, + or ?, is a * unless both are +
+	// or both are ?
+	switch {
+	case n.Min == inner.Min && n.Max == inner.Max:
+	case n.Min == 1 && inner.Min == 1:
+		n.Min, n.Max = 1, -1
+	default:
+		n.Min, n.Max = 0, -1
+	}
+	n.Sub = inner.Sub
+
+	return n
+}
+
+// isSimpleRepeat reports whether n is a *, + or ? which isn't possessive.
+func isSimpleRepeat(n Repeat) bool {
+	return !n.Possessive && n.Min <= 1 && (n.Max == -1 || n.Max == 1)
+}
+
+func (s simplifier) concat(n Concat) Node {
+	subs := make([]Node, len(n.Subs))
+	for i, sub := range n.Subs {
+		subs[i] = s.simplify(sub)
+	}
+
+	return concatOf(subs)
+}
+
+// concatOf returns the concatenation of subs, without a Concat when there
+// are less than two once the empty ones are dropped.
+func concatOf(subs []Node) Node {
+	flat := []Node{}
+	for _, sub := range subs {
+		switch sub := sub.(type) {
+		case Empty:
+		case Concat:
+			flat = append(flat, sub.Subs...)
+		default:
+			flat = append(flat, sub)
+		}
+	}
+	subs = flat
+
+	switch len(subs) {
+	case 0:
+		return Empty{}
+	case 1:
+		return subs[0]
+	}
+
+	return Concat{Subs: subs}
+}
+
+func (s simplifier) alternate(n Alternate) Node {
+	subs := make([]Node, len(n.Subs))
+	for i, sub := range n.Subs {
+		subs[i] = s.simplify(sub)
+	}
+
+	return s.alternateOf(subs)
+}
+
+// alternateOf returns the alternation of subs, which are simplified already.
+func (s simplifier) alternateOf(alternatives []Node) Node {
+	subs := []Node{}
+	for _, sub := range alternatives {
+		if alternate, ok := sub.(Alternate); ok {
+			subs = append(subs, alternate.Subs...)
+		} else {
+			subs = append(subs, sub)
+		}
+	}
+	subs = s.mergeChars(s.factor(subs))
+
+	switch {
+	case len(subs) == 1:
+		return subs[0]
+	case subs[0] == (Empty{}) && !slices.Contains(subs[1:], Node(Empty{})):
+		// the empty string is preferred: a lazy ?
+		return s.repeatOf(Repeat{Sub: s.alternateOf(subs[1:]), Min: 0, Max: 1, Lazy: true})
+	case subs[len(subs)-1] == (Empty{}) && !slices.Contains(subs[:len(subs)-1], Node(Empty{})):
+		return s.repeatOf(Repeat{Sub: s.alternateOf(subs[:len(subs)-1]), Min: 0, Max: 1})
+	}
+
+	return Alternate{Subs: subs}
+}
+
+// factor replaces each run of alternatives starting with the same nodes, by
+// those nodes followed by an alternation of what comes after them.
+func (s simplifier) factor(subs []Node) []Node {
+	factored := []Node{}
+	for i := 0; i < len(subs); {
+		prefix := fixedPrefix(subs[i])
+		j := i + 1
+		for ; j < len(subs) && len(prefix) > 0; j++ {
+			common := commonPrefix(prefix, fixedPrefix(subs[j]))
+			if common == 0 {
+				break
+			}
+			prefix = prefix[:common]
+		}
+		if j-i < 2 || len(prefix) == 0 {
+			factored = append(factored, subs[i])
+			i++
+			continue
+		}
+
+		rests := []Node{}
+		for _, sub := range subs[i:j] {
+			rests = append(rests, dropPrefix(sub, len(prefix)))
+		}
+		factored = append(factored, concatOf(append(slices.Clip(prefix), s.alternateOf(rests))))
+		i = j
+	}
+
+	return factored
+}
+
+// fixedPrefix returns the nodes n starts with which match a fixed number of
+// characters in a single way. Moving an alternation after them doesn't
+// change the order the alternatives are tried in.
+func fixedPrefix(n Node) []Node {
+	subs := []Node{n}
+	if concat, ok := n.(Concat); ok {
+		subs = concat.Subs
+	}
+	for i, sub := range subs {
+		switch sub.(type) {
+		case Literal, CharClass, AnyChar, Anchor:
+		default:
+			return subs[:i]
+		}
+	}
+
+	return subs
+}
+
+func commonPrefix(a []Node, b []Node) int {
+	i := 0
+	for i < len(a) && i < len(b) && reflect.DeepEqual(a[i], b[i]) {
+		i++
+	}
+
+	return i
+}
+
+// dropPrefix returns what matches after the first k nodes of n.
+func dropPrefix(n Node, k int) Node {
+	if concat, ok := n.(Concat); ok {
+		return concatOf(concat.Subs[k:])
+	}
+
+	return Empty{}
+}
+
+// mergeChars replaces each run of alternatives matching a single character
+// by a class. They all match as much, so the order they're tried in doesn't
+// matter.
+func (s simplifier) mergeChars(subs []Node) []Node {
+	merged := []Node{}
+	for i := 0; i < len(subs); {
+		ranges, ok := s.charRanges(subs[i])
+		j := i + 1
+		for ; ok && j < len(subs); j++ {
+			next, ok := s.charRanges(subs[j])
+			if !ok {
+				break
+			}
+			ranges = append(ranges, next...)
+		}
+		if !ok || j-i < 2 {
+			merged = append(merged, subs[i])
+			i++
+			continue
+		}
+		merged = append(merged, CharClass{Ranges: mergeRanges(ranges)})
+		i = j
+	}
+
+	return merged
+}
+
+// charRanges returns the characters matched by n, false if it doesn't always
+// match a single character of a class.
+func (s simplifier) charRanges(n Node) ([]CharRange, bool) {
+	switch n := n.(type) {
+	case Literal:
+		// in byte mode it matches the bytes of its UTF-8 encoding
+		if s.decoder.byteMode && n.Char > 0xFF {
+			return nil, false
+		}
+		ranges := []CharRange{}
+		chars := []rune{n.Char}
+		if n.Fold {
+			chars = s.decoder.foldOrbit(n.Char)
+		}
+		for _, c := range chars {
+			ranges = append(ranges, CharRange{from: c, to: c})
+		}
+		return ranges, true
+	case CharClass:
+		if n.Negated {
+			return nil, false
+		}
+		return slices.Clone(n.Ranges), true
+	}
+
+	return nil, false
+}
+
+// mergeLiterals replaces the runs of literals in n by Texts.
+func mergeLiterals(n Node) Node {
+	switch n := n.(type) {
+	case Group:
+		n.Sub = mergeLiterals(n.Sub)
+		return n
+	case Lookaround:
+		n.Sub = mergeLiterals(n.Sub)
+		return n
+	case Repeat:
+		n.Sub = mergeLiterals(n.Sub)
+		return n
+	case Alternate:
+		subs := make([]Node, len(n.Subs))
+		for i, sub := range n.Subs {
+			subs[i] = mergeLiterals(sub)
+		}
+		return Alternate{Subs: subs}
+	case Concat:
+		subs := []Node{}
+		for i := 0; i < len(n.Subs); i++ {
+			literal, ok := n.Subs[i].(Literal)
+			if !ok {
+				subs = append(subs, mergeLiterals(n.Subs[i]))
+				continue
+			}
+			text := Text{Chars: []rune{literal.Char}, Fold: literal.Fold}
+			for i+1 < len(n.Subs) {
+				next, ok := n.Subs[i+1].(Literal)
+				if !ok || next.Fold != text.Fold {
+					break
+				}
+				text.Chars = append(text.Chars, next.Char)
+				i++
+			}
+			if len(text.Chars) == 1 {
+				subs = append(subs, literal)
+			} else {
+				subs = append(subs, text)
+			}
+		}
+		return concatOf(subs)
+	}
+
+	return n
+}
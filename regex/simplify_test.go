@@ -0,0 +1,149 @@
+package regex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimplify(t *testing.T) {
+	data := []struct {
+		pattern    string
+		simplified string
+	}{
+		{"abc", "abc"},
+		{"a|b|c", "[a-c]"},
+		{"a|[bc]|\\d", "[0-9a-c]"},
+		{"(?i)a|b", "[ABab]"},
+		{"a|bc|d|e", "a|bc|[de]"},
+		{"[^a]|b", "[^a]|b"},
+		{"foo|foobar", "foo(?:bar)??"},
+		{"foobar|foo", "foo(?:bar)?"},
+		{"foo|fob|bar", "fo[bo]|bar"},
+		{"abc|abd|ax", "a(?:b[cd]|x)"},
+		{"^a|^b", "^[ab]"},
+		{"a(b)|a(c)", "a(?:(b)|(c))"},
+		{"a*b|a*c", "a*b|a*c"},
+		{"(?:a*)*", "a*"},
+		{"(?:a+)+", "a+"},
+		{"(?:a?)?", "a?"},
+		{"(?:a+)?", "a*"},
+		{"(?:a*?)+?", "a*?"},
+		{"(?:a*?)*", "(?:a*?)*"},
+		{"(?:a*)*+", "(?:a*)*+"},
+		{"(a*)*", "(a*)*"},
+		{"a(?:)b", "ab"},
+		{"a\\Q\\Eb", "ab"},
+		{"(?:)*", ""},
+		{"a(?=)b", "ab"},
+		{"a(?!)b", "a(?!)b"},
+		{"(?>)a", "a"},
+		{"()a", "()a"},
+		{"a|", "a?"},
+		{"|a", "a??"},
+		{"a||b", "a||b"},
+		{"(?:ab){1}", "ab"},
+		{"(?i)ab1", "(?i:ab)1"},
+		{"x(?:ab)*y", "x(?:ab)*y"},
+	}
+
+	for _, d := range data {
+		node, err := Parse(d.pattern)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", d.pattern, err)
+		}
+		if got := Simplify(node).String(); got != d.simplified {
+			t.Errorf("Expected %q to simplify to %q, got %q", d.pattern, d.simplified, got)
+		}
+	}
+}
+
+func TestSimplifyMatchesStdlib(t *testing.T) {
+	patterns := []string{
+		"foo|foobar", "foobar|foo", "foo|fob|bar", "(a|ab)(c|bcd)(d*)", "a(?:b|c)|ad", "(?i)k|x|[a-c]",
+		"x(?:|y)z?", "(?:a+)?b", "a(b)|a(c)|a", "^foo|^bar|baz$", ".a|.b|..", "\\bthe|\\bthen|\\bthere",
+		"(?:ab|a)(?:bc|c)", "(?:x*)*y|x+",
+	}
+	inputs := []string{"foobar fob barfoo", "abcd abd acd ad", "k K x b B", "xz xyz xyzz", "aab b ab ac a", "the then there thee"}
+
+	for _, pattern := range patterns {
+		std := regexp.MustCompile(pattern)
+		re := MustCompile(pattern)
+		for _, input := range inputs {
+			want := std.FindAllSubmatchIndex([]byte(input), -1)
+			got := re.FindAllSubmatchIndex([]byte(input), -1)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Expected %q on %q to match at %v, got %v", pattern, input, want, got)
+			}
+		}
+	}
+}
+
+func TestSimplifyShrinksGeneratedPatterns(t *testing.T) {
+	words := []string{}
+	for i := range 500 {
+		words = append(words, fmt.Sprintf("item%03d", i))
+	}
+	pattern := `\b(?:` + strings.Join(words, "|") + `)\b`
+
+	node, err := Parse(pattern)
+	if err != nil {
+		t.Fatalf("Error parsing the pattern: %v", err)
+	}
+	raw, _ := compileNode(node, 0, decoder{})
+	simplified, _ := compileNode(Simplify(node), 0, decoder{})
+	if len(simplified.States)*4 > len(raw.States) {
+		t.Errorf("Expected simplifying to cut the %v states by 4 at least, got %v", len(raw.States), len(simplified.States))
+	}
+
+	re := MustCompile(pattern)
+	matches := re.FindAllString("item007 item7 item499 item500 item0420", -1)
+	if !stringSliceEqual(matches, []string{"item007", "item499"}) {
+		t.Errorf("Expected [item007 item499], got: %v", matches)
+	}
+}
+
+func TestCompileGeneratedPatternsScales(t *testing.T) {
+	generators := map[string]func(n int) string{
+		"alternation": func(n int) string {
+			words := []string{}
+			for i := range n {
+				words = append(words, fmt.Sprintf("w%dx", i))
+			}
+			return strings.Join(words, "|") + `|\d+z`
+		},
+		"classes": func(n int) string {
+			return strings.Repeat("[ab]", n)
+		},
+	}
+
+	// the fastest of a few compilations, to leave the GC out of it
+	compileTime := func(pattern string) time.Duration {
+		fastest := time.Duration(-1)
+		for range 3 {
+			start := time.Now()
+			MustCompile(pattern)
+			if elapsed := time.Since(start); fastest < 0 || elapsed < fastest {
+				fastest = elapsed
+			}
+		}
+		return fastest
+	}
+
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			small := compileTime(generate(500))
+			large := compileTime(generate(4000))
+
+			// 8 times the size, 64 times the time if it were quadratic
+			if large > 24*small && large > 50*time.Millisecond {
+				t.Errorf("Expected compiling 8 times more to take about 8 times longer, took %v instead of %v", large, small)
+			}
+			if large > time.Second {
+				t.Errorf("Expected the compilation to take less than a second, took: %v", large)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package regex
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ------------------ Unicode classes ------------------
+// \p{Name} matches the characters of a Unicode general category (L, Lu, N,
+// ...) or script (Greek, Han, ...), \P{Name} and \p{^Name} the others. A one
+// letter category can skip the braces: \pL. Any is every character.
+//
+// The sets come from the standard library's unicode range tables and work
+// on their own as well as inside brackets.
+// ---------------------------------------------
+
+// parseUnicodeClass parses what follows \p or \P, esc being the p or P, and
+// returns the characters it stands for.
+func (p *Parser) parseUnicodeClass(esc byte) ([]CharRange, error) {
+	start := p.pos - 2
+	if p.isEnd() {
+		return nil, p.errorAt(ErrInvalidCharClass, start, p.pos)
+	}
+
+	_, width := utf8.DecodeRuneInString(p.pattern[p.pos:])
+	name := p.pattern[p.pos : p.pos+width]
+	p.pos += width
+	if name == "{" {
+		end := strings.IndexByte(p.pattern[p.pos:], '}')
+		if end < 0 {
+			return nil, p.errorAt(ErrInvalidCharClass, start, len(p.pattern))
+		}
+		name = p.pattern[p.pos : p.pos+end]
+		p.pos += end + 1
+	}
+
+	negated := esc == 'P'
+	if strings.HasPrefix(name, "^") {
+		negated = !negated
+		name = name[1:]
+	}
+
+	ranges, ok := unicodeClass(name)
+	if !ok {
+		return nil, p.errorAt(ErrInvalidCharClass, start, p.pos)
+	}
+	if p.flags.caseInsensitive {
+		ranges = p.foldRanges(ranges)
+	}
+	if negated {
+		return negateRanges(ranges), nil
+	}
+
+	return ranges, nil
+}
+
+// unicodeClass returns the characters of the category or script name.
+func unicodeClass(name string) ([]CharRange, bool) {
+	if name == "Any" {
+		return []CharRange{{from: 0, to: unicode.MaxRune}}, true
+	}
+	table, ok := unicode.Categories[name]
+	if !ok {
+		table, ok = unicode.Scripts[name]
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return tableRanges(table), true
+}
+
+// tableRanges converts a range table, a range with a stride above 1 becomes
+// one range per character.
+func tableRanges(table *unicode.RangeTable) []CharRange {
+	ranges := []CharRange{}
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			ranges = append(ranges, CharRange{from: lo, to: hi})
+			return
+		}
+		for c := lo; c <= hi; c += stride {
+			ranges = append(ranges, CharRange{from: c, to: c})
+		}
+	}
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+
+	return ranges
+}